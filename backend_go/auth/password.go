@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordCost is the bcrypt work factor used for newly hashed passwords.
+const PasswordCost = 12
+
+// HashPassword returns a bcrypt hash of the given plaintext password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsHashed reports whether the stored value is a bcrypt hash. Rows created
+// before passwords were hashed still hold the plaintext password.
+func IsHashed(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword compares a plaintext password against the stored value.
+// Legacy plaintext values are compared in constant time as well, so callers
+// can detect them with NeedsRehash and upgrade them after a successful check.
+func CheckPassword(stored, password string) bool {
+	if stored == "" {
+		return false
+	}
+	if IsHashed(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// NeedsRehash reports whether a stored password should be replaced by a
+// fresh hash, either because it is still plaintext or uses an outdated cost.
+func NeedsRehash(stored string) bool {
+	if !IsHashed(stored) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		return true
+	}
+	return cost < PasswordCost
+}
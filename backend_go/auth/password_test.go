@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHashed(hash) || strings.Contains(hash, "correct horse") {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+	if !CheckPassword(hash, "correct horse battery staple") {
+		t.Error("correct password rejected")
+	}
+	if CheckPassword(hash, "Correct horse battery staple") {
+		t.Error("wrong password accepted")
+	}
+	if NeedsRehash(hash) {
+		t.Error("fresh hash needs a rehash")
+	}
+	if other, _ := HashPassword("correct horse battery staple"); other == hash {
+		t.Error("hash is not salted")
+	}
+}
+
+func TestCheckPasswordLegacyValues(t *testing.T) {
+	if !CheckPassword("geheim", "geheim") || CheckPassword("geheim", "Geheim") {
+		t.Error("plaintext comparison wrong")
+	}
+	if CheckPassword("", "") {
+		t.Error("empty stored password must never match")
+	}
+	if !NeedsRehash("geheim") {
+		t.Error("plaintext values must be rehashed")
+	}
+}
+
+func TestNeedsRehashOnLowerCost(t *testing.T) {
+	weak, err := bcrypt.GenerateFromPassword([]byte("pw"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(string(weak)) {
+		t.Error("hash with a lower cost must be rehashed")
+	}
+	if !CheckPassword(string(weak), "pw") {
+		t.Error("older hashes must still verify")
+	}
+}
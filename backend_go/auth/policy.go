@@ -0,0 +1,51 @@
+package auth
+
+import "errors"
+
+// ErrForbidden is returned when a principal may see a resource but not perform the action.
+var ErrForbidden = errors.New("forbidden")
+
+// Principal is the authenticated actor on whose behalf an operation runs.
+type Principal struct {
+	UserID int
+	Role   string
+}
+
+// IsAdmin reports whether the principal has the admin role.
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// ExpensePolicy decides whose expenses a principal may read and modify.
+// The default only grants access to the principal's own data; a shared
+// household policy can widen ReadableOwners and CanWrite later on.
+type ExpensePolicy interface {
+	// ReadableOwners returns the user IDs whose expenses the principal may read.
+	ReadableOwners(p Principal) []int
+	// CanWrite reports whether the principal may create, change or delete
+	// expenses owned by ownerID.
+	CanWrite(p Principal, ownerID int) bool
+}
+
+// OwnerOnlyPolicy restricts every principal to its own expenses.
+type OwnerOnlyPolicy struct{}
+
+// ReadableOwners implements ExpensePolicy.
+func (OwnerOnlyPolicy) ReadableOwners(p Principal) []int {
+	return []int{p.UserID}
+}
+
+// CanWrite implements ExpensePolicy.
+func (OwnerOnlyPolicy) CanWrite(p Principal, ownerID int) bool {
+	return p.UserID == ownerID
+}
+
+// CanRead reports whether the policy lets the principal read expenses of ownerID.
+func CanRead(policy ExpensePolicy, p Principal, ownerID int) bool {
+	for _, id := range policy.ReadableOwners(p) {
+		if id == ownerID {
+			return true
+		}
+	}
+	return false
+}
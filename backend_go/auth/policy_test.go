@@ -0,0 +1,14 @@
+package auth
+
+import "testing"
+
+func TestOwnerOnlyPolicy(t *testing.T) {
+	policy := OwnerOnlyPolicy{}
+	member := Principal{UserID: 1, Role: RoleMember}
+	if !CanRead(policy, member, 1) || CanRead(policy, member, 2) {
+		t.Error("members may read exactly their own expenses")
+	}
+	if !policy.CanWrite(member, 1) || policy.CanWrite(member, 2) {
+		t.Error("members may write exactly their own expenses")
+	}
+}
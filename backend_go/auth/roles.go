@@ -0,0 +1,13 @@
+package auth
+
+// Roles a user can have. Every account is a member; admins may additionally
+// manage other accounts and use the /admin endpoints.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "strings"
+
+// Scopes of personal API tokens. Login sessions are not restricted by scopes.
+const (
+	ScopeExpensesRead  = "expenses:read"
+	ScopeExpensesWrite = "expenses:write"
+)
+
+// APITokenPrefix marks personal API tokens so they can be told apart from JWTs.
+const APITokenPrefix = "lvt_"
+
+var knownScopes = []string{ScopeExpensesRead, ScopeExpensesWrite}
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	for _, s := range knownScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIToken reports whether a bearer token is a personal API token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestValidScope(t *testing.T) {
+	for scope, want := range map[string]bool{
+		ScopeExpensesRead:  true,
+		ScopeExpensesWrite: true,
+		"expenses:*":       false,
+		"admin":            false,
+		"":                 false,
+	} {
+		if ValidScope(scope) != want {
+			t.Errorf("ValidScope(%q) != %v", scope, want)
+		}
+	}
+}
+
+func TestIsAPIToken(t *testing.T) {
+	plain, hash, err := NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsAPIToken(plain) || IsAPIToken("eyJhbGciOiJIUzI1NiJ9.e30.x") || !IsAPIToken(APITokenPrefix+plain) {
+		t.Error("IsAPIToken misclassifies tokens")
+	}
+	if HashToken(plain) != hash || len(hash) != 64 {
+		t.Error("NewOpaqueToken hash does not match HashToken")
+	}
+}
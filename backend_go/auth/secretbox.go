@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// SecretBox encrypts small secrets, such as TOTP seeds, with AES-256-GCM
+// before they are written to the database.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox from a 32 byte key.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+	if allZero(key) {
+		return nil, errors.New("encryption key must not be all zeros, generate one with `openssl rand -base64 32`")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// NewSecretBoxFromBase64 creates a SecretBox from a base64 encoded 32 byte key,
+// e.g. the output of `openssl rand -base64 32`.
+func NewSecretBoxFromBase64(encoded string) (*SecretBox, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key: %w", err)
+	}
+	return NewSecretBox(key)
+}
+
+// Seal encrypts plaintext; the random nonce is prepended to the ciphertext.
+// additionalData binds the ciphertext to its owner, e.g. the user ID.
+func (b *SecretBox) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (b *SecretBox) Open(sealed, additionalData []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+	return b.aead.Open(nil, sealed[:size], sealed[size:], additionalData)
+}
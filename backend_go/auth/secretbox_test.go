@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testBox(t *testing.T) *SecretBox {
+	t.Helper()
+	box, err := NewSecretBoxFromBase64("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box := testBox(t)
+	plaintext := []byte("JBSWY3DPEHPK3PXP")
+	sealed, err := box.Seal(plaintext, []byte("totp:user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("plaintext visible in the sealed value")
+	}
+	opened, err := box.Open(sealed, []byte("totp:user:1"))
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("round trip failed: %q, %v", opened, err)
+	}
+
+	again, _ := box.Seal(plaintext, []byte("totp:user:1"))
+	if bytes.Equal(again, sealed) {
+		t.Fatal("nonce reused")
+	}
+}
+
+func TestSecretBoxRejectsTampering(t *testing.T) {
+	box := testBox(t)
+	sealed, _ := box.Seal([]byte("secret"), []byte("totp:user:1"))
+
+	if _, err := box.Open(sealed, []byte("totp:user:2")); err == nil {
+		t.Error("opened with the wrong additional data")
+	}
+	flipped := append([]byte(nil), sealed...)
+	flipped[len(flipped)-1] ^= 1
+	if _, err := box.Open(flipped, []byte("totp:user:1")); err == nil {
+		t.Error("opened a modified ciphertext")
+	}
+	if _, err := box.Open(sealed[:5], []byte("totp:user:1")); err == nil {
+		t.Error("opened a truncated ciphertext")
+	}
+}
+
+func TestNewSecretBoxRejectsBadKeys(t *testing.T) {
+	for name, key := range map[string]string{
+		"too short": base64.StdEncoding.EncodeToString(make([]byte, 16)),
+		"all zeros": base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		"no base64": "not base64!",
+	} {
+		if _, err := NewSecretBoxFromBase64(key); err == nil {
+			t.Errorf("%s: key accepted", name)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultAccessTokenTTL is used when JWT_ACCESS_TTL is not set.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// DefaultRefreshTokenTTL is used when JWT_REFRESH_TTL is not set.
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// tokenIssuer is written into the iss claim of every access token.
+const tokenIssuer = "backend_go"
+
+// MFATokenTTL is how long the second login step may take after the password was accepted.
+const MFATokenTTL = 5 * time.Minute
+
+// Token types kept in the typ claim, so a token of one kind is never accepted as another.
+const (
+	tokenTypeAccess = "access"
+	tokenTypeMFA    = "mfa"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired or not signed by us.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the JWT claims of an access token. The user ID is kept in the subject,
+// the refresh token family the token was issued for in SessionID.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	Type      string `json:"typ"`
+	Role      string `json:"role,omitempty"`
+}
+
+// UserID returns the user ID stored in the subject claim.
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// TokenManager issues and verifies signed access tokens. Tokens are signed with
+// Ed25519 if a signing key is configured, so other services can verify them
+// with the public key from the JWKS; otherwise with HS256 and a shared secret.
+type TokenManager struct {
+	secret     []byte
+	signingKey ed25519.PrivateKey
+	keyID      string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenManager creates a TokenManager that signs tokens with HS256.
+func NewTokenManager(secret []byte, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// NewEd25519TokenManager creates a TokenManager that signs tokens with EdDSA.
+func NewEd25519TokenManager(key ed25519.PrivateKey, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		signingKey: key,
+		keyID:      ed25519KeyID(key.Public().(ed25519.PublicKey)),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// NewTokenManagerFromEnv reads JWT_SIGNING_KEY (base64 encoded 32 byte Ed25519
+// seed) or, if that is not set, JWT_SECRET, plus the optional JWT_ACCESS_TTL and
+// JWT_REFRESH_TTL (Go durations such as "15m" or "720h").
+func NewTokenManagerFromEnv() (*TokenManager, error) {
+	accessTTL, err := durationFromEnv("JWT_ACCESS_TTL", DefaultAccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := durationFromEnv("JWT_REFRESH_TTL", DefaultRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded := os.Getenv("JWT_SIGNING_KEY"); encoded != "" {
+		seed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("JWT_SIGNING_KEY must be a base64 encoded 32 byte Ed25519 seed")
+		}
+		if allZero(seed) {
+			return nil, errors.New("JWT_SIGNING_KEY must not be all zeros, generate one with `openssl rand -base64 32`")
+		}
+		return NewEd25519TokenManager(ed25519.NewKeyFromSeed(seed), accessTTL, refreshTTL), nil
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) < 32 {
+		return nil, errors.New("JWT_SIGNING_KEY or JWT_SECRET (at least 32 characters) must be set")
+	}
+	return NewTokenManager([]byte(secret), accessTTL, refreshTTL), nil
+}
+
+// allZero reports whether key consists only of zero bytes, i.e. a placeholder
+// such as "AAAA...A=" that was copied instead of a generated key.
+func allZero(key []byte) bool {
+	for _, b := range key {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ed25519KeyID returns the RFC 7638 thumbprint of the public key, used as kid.
+func ed25519KeyID(pub ed25519.PublicKey) string {
+	x := base64.RawURLEncoding.EncodeToString(pub)
+	sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKS returns the public keys other services need to verify our tokens. It is
+// empty when tokens are signed with a shared secret.
+func (m *TokenManager) JWKS() []JWK {
+	if m.signingKey == nil {
+		return []JWK{}
+	}
+	return []JWK{{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(m.signingKey.Public().(ed25519.PublicKey)),
+		Kid: m.keyID,
+		Alg: jwt.SigningMethodEdDSA.Alg(),
+		Use: "sig",
+	}}
+}
+
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return parsed, nil
+}
+
+// AccessTokenTTL returns how long issued access tokens stay valid.
+func (m *TokenManager) AccessTokenTTL() time.Duration {
+	return m.accessTTL
+}
+
+// RefreshTokenTTL returns how long issued refresh tokens stay valid.
+func (m *TokenManager) RefreshTokenTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// IssueAccessToken creates a signed access token for the given user and session.
+// The role is embedded so route guards do not need a database lookup. Role
+// changes through the admin API revoke the user's sessions, which invalidates
+// older tokens; the admin bootstrap only promotes, and the new role is picked
+// up with the next refresh, which reads the role from the database.
+func (m *TokenManager) IssueAccessToken(userID int, sessionID, role string) (string, time.Time, error) {
+	return m.issue(userID, sessionID, tokenTypeAccess, role, m.accessTTL)
+}
+
+// ParseAccessToken verifies the signature and expiry of a token and returns its claims.
+func (m *TokenManager) ParseAccessToken(token string) (*Claims, error) {
+	return m.parse(token, tokenTypeAccess)
+}
+
+// IssueMFAToken creates a short-lived token proving that the password of the
+// user was accepted. It can only be exchanged for a session together with a
+// valid second factor.
+func (m *TokenManager) IssueMFAToken(userID int) (string, time.Time, error) {
+	return m.issue(userID, "", tokenTypeMFA, "", MFATokenTTL)
+}
+
+// ParseMFAToken verifies a token created by IssueMFAToken.
+func (m *TokenManager) ParseMFAToken(token string) (*Claims, error) {
+	return m.parse(token, tokenTypeMFA)
+}
+
+func (m *TokenManager) issue(userID int, sessionID, tokenType, role string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		SessionID: sessionID,
+		Type:      tokenType,
+		Role:      role,
+	}
+
+	var signed string
+	var err error
+	if m.signingKey != nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = m.keyID
+		signed, err = token.SignedString(m.signingKey)
+	} else {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func (m *TokenManager) parse(token, tokenType string) (*Claims, error) {
+	key, method := interface{}(m.secret), jwt.SigningMethodHS256.Alg()
+	if m.signingKey != nil {
+		key, method = m.signingKey.Public(), jwt.SigningMethodEdDSA.Alg()
+	}
+
+	claims := &Claims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{method}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !parsed.Valid || claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testEd25519Manager(seed byte) *TokenManager {
+	key := ed25519.NewKeyFromSeed([]byte(strings.Repeat(string(rune('a'+seed)), ed25519.SeedSize)))
+	return NewEd25519TokenManager(key, time.Minute, time.Hour)
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	for name, m := range map[string]*TokenManager{
+		"HS256":   NewTokenManager(testSecret, time.Minute, time.Hour),
+		"Ed25519": testEd25519Manager(0),
+	} {
+		token, expiresAt, err := m.IssueAccessToken(42, "family-1", RoleAdmin)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if time.Until(expiresAt) > time.Minute || time.Until(expiresAt) < 50*time.Second {
+			t.Errorf("%s: unexpected expiry %s", name, expiresAt)
+		}
+		claims, err := m.ParseAccessToken(token)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if id, _ := claims.UserID(); id != 42 || claims.SessionID != "family-1" || claims.Role != RoleAdmin {
+			t.Errorf("%s: unexpected claims %+v", name, claims)
+		}
+	}
+}
+
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	m := NewTokenManager(testSecret, time.Minute, time.Hour)
+	mfa, _, _ := m.IssueMFAToken(7)
+	if _, err := m.ParseAccessToken(mfa); err == nil {
+		t.Error("MFA token accepted as access token")
+	}
+	if _, err := m.ParseMFAToken(mfa); err != nil {
+		t.Errorf("MFA token rejected: %v", err)
+	}
+	access, _, _ := m.IssueAccessToken(7, "s", RoleMember)
+	if _, err := m.ParseMFAToken(access); err == nil {
+		t.Error("access token accepted as MFA token")
+	}
+}
+
+func TestParseAccessTokenRejects(t *testing.T) {
+	m := NewTokenManager(testSecret, time.Minute, time.Hour)
+	valid, _, _ := m.IssueAccessToken(1, "s", RoleMember)
+
+	expired, _, _ := NewTokenManager(testSecret, -time.Minute, time.Hour).IssueAccessToken(1, "s", RoleMember)
+	otherKey, _, _ := NewTokenManager([]byte("ffffffffffffffffffffffffffffffff"), time.Minute, time.Hour).IssueAccessToken(1, "s", RoleMember)
+	ed, _, _ := testEd25519Manager(0).IssueAccessToken(1, "s", RoleMember)
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: tokenIssuer, Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		Type:             tokenTypeAccess,
+	}
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	claims.Issuer = "someone-else"
+	foreignIssuer, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	claims.Issuer, claims.ExpiresAt = tokenIssuer, nil
+	noExpiry, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	claims.ExpiresAt, claims.Subject = jwt.NewNumericDate(time.Now().Add(time.Minute)), "admin"
+	badSubject, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"backend_go","sub":"2","typ":"access","role":"admin","exp":9999999999}`)) + "." + parts[2]
+
+	for name, token := range map[string]string{
+		"expired":        expired,
+		"other secret":   otherKey,
+		"other method":   ed,
+		"alg none":       none,
+		"foreign issuer": foreignIssuer,
+		"no expiry":      noExpiry,
+		"bad subject":    badSubject,
+		"tampered":       tampered,
+		"garbage":        "not.a.token",
+	} {
+		if _, err := m.ParseAccessToken(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestEd25519KeyRotation(t *testing.T) {
+	old, current := testEd25519Manager(0), testEd25519Manager(1)
+	token, _, _ := old.IssueAccessToken(1, "s", RoleMember)
+	if _, err := current.ParseAccessToken(token); err == nil {
+		t.Fatal("token of the old key accepted after rotation")
+	}
+	if old.JWKS()[0].Kid == current.JWKS()[0].Kid {
+		t.Fatal("rotated key has the same kid")
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if !strings.Contains(string(header), `"kid":"`+old.JWKS()[0].Kid+`"`) {
+		t.Fatalf("kid missing in header %s", header)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	if keys := NewTokenManager(testSecret, time.Minute, time.Hour).JWKS(); len(keys) != 0 {
+		t.Fatalf("HS256 must not publish keys: %+v", keys)
+	}
+	keys := testEd25519Manager(0).JWKS()
+	if len(keys) != 1 || keys[0].Kty != "OKP" || keys[0].Crv != "Ed25519" || keys[0].Alg != "EdDSA" || keys[0].Use != "sig" {
+		t.Fatalf("unexpected JWKS %+v", keys)
+	}
+}
+
+func TestNewTokenManagerFromEnv(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TTL", "")
+	t.Setenv("JWT_REFRESH_TTL", "")
+	t.Setenv("JWT_SECRET", "")
+
+	t.Setenv("JWT_SIGNING_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if _, err := NewTokenManagerFromEnv(); err == nil {
+		t.Error("all-zero signing key accepted")
+	}
+	t.Setenv("JWT_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	if _, err := NewTokenManagerFromEnv(); err == nil {
+		t.Error("short signing key accepted")
+	}
+	t.Setenv("JWT_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	m, err := NewTokenManagerFromEnv()
+	if err != nil || len(m.JWKS()) != 1 || m.AccessTokenTTL() != DefaultAccessTokenTTL {
+		t.Errorf("valid signing key: %v", err)
+	}
+
+	t.Setenv("JWT_SIGNING_KEY", "")
+	t.Setenv("JWT_SECRET", "too-short")
+	if _, err := NewTokenManagerFromEnv(); err == nil {
+		t.Error("short JWT_SECRET accepted")
+	}
+	t.Setenv("JWT_SECRET", string(testSecret))
+	t.Setenv("JWT_ACCESS_TTL", "5m")
+	if m, err := NewTokenManagerFromEnv(); err != nil || m.AccessTokenTTL() != 5*time.Minute {
+		t.Errorf("JWT_SECRET with TTL: %v", err)
+	}
+}
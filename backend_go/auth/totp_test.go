@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the ASCII key "12345678901234567890" of RFC 4226 and RFC 6238 (SHA-1).
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	// Appendix D of RFC 4226: HOTP values for counters 0 to 9
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, expected := range want {
+		code, err := TOTPCode(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("counter %d: got %s, want %s", counter, code, expected)
+		}
+	}
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Appendix B of RFC 6238 (SHA-1); the RFC lists 8 digits, we use the last 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, code, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeRejectsInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Fatal("expected an error for an invalid secret")
+	}
+}
+
+func TestValidateTOTPStepWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	for delta := int64(-3); delta <= 3; delta++ {
+		code, _ := TOTPCode(rfcSecret, current+delta)
+		step, ok := ValidateTOTP(rfcSecret, code, now)
+		inWindow := delta >= -totpSkew && delta <= totpSkew
+		if ok != inWindow {
+			t.Errorf("delta %d: accepted=%v, want %v", delta, ok, inWindow)
+		}
+		if ok && step != current+delta {
+			t.Errorf("delta %d: got step %d, want %d", delta, step, current+delta)
+		}
+	}
+}
+
+func TestValidateTOTPNormalizesInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := ValidateTOTP(rfcSecret, " 287 082 ", now); !ok {
+		t.Error("code with spaces rejected")
+	}
+	for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+}
+
+func TestValidateTOTPOnceRejectsReplays(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+	code, _ := TOTPCode(rfcSecret, current)
+
+	step, ok := ValidateTOTPOnce(rfcSecret, code, now, 0)
+	if !ok || step != current {
+		t.Fatalf("first use rejected: step %d, ok %v", step, ok)
+	}
+	if _, ok := ValidateTOTPOnce(rfcSecret, code, now, step); ok {
+		t.Fatal("the same code was accepted twice")
+	}
+
+	// An older code from the window is refused once a newer one was used
+	previous, _ := TOTPCode(rfcSecret, current-1)
+	if _, ok := ValidateTOTPOnce(rfcSecret, previous, now, current); ok {
+		t.Fatal("an older code was accepted after a newer one")
+	}
+	next, _ := TOTPCode(rfcSecret, current+1)
+	if step, ok := ValidateTOTPOnce(rfcSecret, next, now, current); !ok || step != current+1 {
+		t.Fatalf("the next code was rejected: step %d, ok %v", step, ok)
+	}
+}
+
+func TestNewTOTPSecretRoundTrip(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, err := TOTPCode(secret, TOTPStep(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ValidateTOTP(secret, code, now); !ok {
+		t.Fatal("code of a new secret rejected")
+	}
+	uri := TOTPProvisioningURI(secret, "Leviathan", "max@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Leviathan:max@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected provisioning URI %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+	if got := NormalizeRecoveryCode(" ABCDE-fghjk "); got != "abcdefghjk" {
+		t.Errorf("NormalizeRecoveryCode = %q", got)
+	}
+	if HashToken(NormalizeRecoveryCode("abcde fghjk")) != HashToken(NormalizeRecoveryCode("ABCDE-FGHJK")) {
+		t.Error("spellings of the same code must hash alike")
+	}
+}
@@ -0,0 +1,38 @@
+// Package blobstore stores binary objects such as receipts outside of the
+// expense table. Objects are addressed by slash-separated keys.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no object is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain "..".
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore is a flat key/value store for binary objects.
+type BlobStore interface {
+	// Put stores data under key and replaces an existing object.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the object stored under key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Open returns a reader for the object stored under key or ErrNotFound.
+	// The caller closes it. Use it instead of Get to stream large objects.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the object; deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey accepts relative slash-separated keys like "receipts/3/17/ab12…".
+func validKey(key string) error {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, `\`) {
+		return ErrInvalidKey
+	}
+	return nil
+}
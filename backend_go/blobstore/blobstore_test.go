@@ -0,0 +1,71 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testStore checks the BlobStore contract.
+func testStore(t *testing.T, store BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+	key := "receipts/1/2/abc"
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put: %v", err)
+	}
+	if err := store.Put(ctx, key, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, key, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.Get(ctx, key)
+	if err != nil || string(data) != "second" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+	testOpen(t, store, key, "second")
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing object: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after Delete: %v", err)
+	}
+}
+
+// testOpen reads the object the way http.ServeContent does for a range request.
+func testOpen(t *testing.T, store BlobStore, key, want string) {
+	t.Helper()
+	r, err := store.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil || size != int64(len(want)) {
+		t.Fatalf("size = %d, %v", size, err)
+	}
+	if _, err := r.Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 3)
+	if _, err := io.ReadFull(r, part); err != nil || string(part) != want[2:5] {
+		t.Fatalf("range read = %q, %v", part, err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	all, err := io.ReadAll(r)
+	if err != nil || string(all) != want {
+		t.Fatalf("ReadAll after Seek = %q, %v", all, err)
+	}
+}
@@ -0,0 +1,39 @@
+package blobstore
+
+import (
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// FromEnv returns the store selected by RECEIPT_STORE:
+//
+//	postgres (default)  the blobs table of db
+//	fs                  files below RECEIPT_STORE_DIR
+//	s3                  RECEIPT_S3_ENDPOINT, RECEIPT_S3_BUCKET, RECEIPT_S3_REGION,
+//	                    RECEIPT_S3_ACCESS_KEY, RECEIPT_S3_SECRET_KEY and
+//	                    RECEIPT_S3_PATH_STYLE ("false" for virtual-hosted buckets)
+func FromEnv(db *gorm.DB) (BlobStore, error) {
+	switch kind := os.Getenv("RECEIPT_STORE"); kind {
+	case "", "postgres":
+		return NewPostgresStore(db), nil
+	case "fs":
+		dir := os.Getenv("RECEIPT_STORE_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("RECEIPT_STORE_DIR is required for RECEIPT_STORE=fs")
+		}
+		return NewFSStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("RECEIPT_S3_ENDPOINT"),
+			Region:    os.Getenv("RECEIPT_S3_REGION"),
+			Bucket:    os.Getenv("RECEIPT_S3_BUCKET"),
+			AccessKey: os.Getenv("RECEIPT_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("RECEIPT_S3_SECRET_KEY"),
+			PathStyle: os.Getenv("RECEIPT_S3_PATH_STYLE") != "false",
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown RECEIPT_STORE %q (postgres, fs or s3)", kind)
+	}
+}
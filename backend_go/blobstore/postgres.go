@@ -0,0 +1,75 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// blob is a row of the blobs table (see migration 0006_blob_store).
+type blob struct {
+	Key  string `gorm:"primaryKey"`
+	Data []byte
+}
+
+func (blob) TableName() string {
+	return "blobs"
+}
+
+// PostgresStore keeps the objects in their own table, so scans and dumps of
+// the expense table stay small.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore returns a store in the blobs table of db.
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data"}),
+	}).Create(&blob{Key: key, Data: data}).Error
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var b blob
+	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&b).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return b.Data, nil
+}
+
+// Open reads the whole object, bytea columns cannot be read in parts. Objects
+// that should be streamed belong in the FS or S3 store.
+func (s *PostgresStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	data, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&blob{}).Error
+}
@@ -0,0 +1,41 @@
+package blobstore
+
+import (
+	"backend_go/db"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it; without it the test is skipped.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestPostgresStoreRoundTrip(t *testing.T) {
+	testStore(t, NewPostgresStore(testDB(t)))
+}
+
+func TestPostgresStoreRejectsInvalidKeys(t *testing.T) {
+	store := NewPostgresStore(testDB(t))
+	if err := store.Put(context.Background(), "../x", []byte("x")); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Put = %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package budget computes monthly budget reports: how much of a category's
+// monthly limit is planned, spent and left, optionally carrying unused amounts
+// into the following months.
+package budget
+
+import (
+	"backend_go/recurrence"
+	"math"
+	"time"
+)
+
+// Status of a budget line.
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusExceeded = "exceeded"
+)
+
+// WarningRatio is the share of the planned amount from which a line is a warning.
+const WarningRatio = 0.8
+
+// MaxRolloverMonths limits how far back unused amounts are carried over.
+const MaxRolloverMonths = 120
+
+// Budget is a monthly limit for a category (including its subcategories).
+type Budget struct {
+	ID         int
+	CategoryID int
+	Limit      float64
+	Rollover   bool
+	StartMonth time.Time // first month the budget applies to
+}
+
+// Spending is one expense due in a month with the categories it is assigned to.
+type Spending struct {
+	Month       time.Time
+	Amount      float64
+	CategoryIDs []int
+}
+
+// Line is the state of one budget in a month.
+type Line struct {
+	BudgetID   int
+	CategoryID int
+	Limit      float64
+	Carryover  float64 // unused amount from earlier months
+	Planned    float64 // Limit + Carryover
+	Spent      float64
+	Remaining  float64
+	Status     string
+}
+
+// Report computes the lines for month. children maps a category to its direct
+// subcategories; spending must cover every month from the earliest StartMonth
+// of a rollover budget (at most MaxRolloverMonths back) up to month. Budgets
+// that start after month are left out.
+func Report(month time.Time, budgets []Budget, children map[int][]int, spending []Spending) []Line {
+	month = firstOfMonth(month)
+	spent := map[int]map[time.Time]float64{} // budget ID -> month -> amount
+	for _, b := range budgets {
+		covered := subtree(b.CategoryID, children)
+		perMonth := map[time.Time]float64{}
+		for _, s := range spending {
+			if matches(s.CategoryIDs, covered) {
+				perMonth[firstOfMonth(s.Month)] += s.Amount
+			}
+		}
+		spent[b.ID] = perMonth
+	}
+
+	lines := []Line{}
+	for _, b := range budgets {
+		start := firstOfMonth(b.StartMonth)
+		if start.After(month) {
+			continue
+		}
+		line := Line{BudgetID: b.ID, CategoryID: b.CategoryID, Limit: b.Limit}
+		if b.Rollover {
+			if earliest := month.AddDate(0, -MaxRolloverMonths, 0); start.Before(earliest) {
+				start = earliest
+			}
+			for m := start; m.Before(month); m = m.AddDate(0, 1, 0) {
+				line.Carryover = math.Max(0, line.Carryover+b.Limit-spent[b.ID][m])
+			}
+		}
+		line.Planned = line.Limit + line.Carryover
+		line.Spent = spent[b.ID][month]
+		line.Remaining = line.Planned - line.Spent
+		line.Status = status(line.Planned, line.Spent)
+
+		line.Carryover, line.Planned = Round(line.Carryover), Round(line.Planned)
+		line.Spent, line.Remaining = Round(line.Spent), Round(line.Remaining)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// EarliestMonth returns the first month whose spending Report needs for month.
+func EarliestMonth(month time.Time, budgets []Budget) time.Time {
+	earliest := firstOfMonth(month)
+	limit := earliest.AddDate(0, -MaxRolloverMonths, 0)
+	for _, b := range budgets {
+		start := firstOfMonth(b.StartMonth)
+		if b.Rollover && start.Before(earliest) {
+			earliest = start
+		}
+	}
+	if earliest.Before(limit) {
+		return limit
+	}
+	return earliest
+}
+
+func status(planned, spent float64) string {
+	switch {
+	case spent > planned:
+		return StatusExceeded
+	case spent >= planned*WarningRatio:
+		return StatusWarning
+	default:
+		return StatusOK
+	}
+}
+
+// subtree returns the category and all categories below it.
+func subtree(root int, children map[int][]int) map[int]bool {
+	covered := map[int]bool{}
+	queue := []int{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if covered[id] {
+			continue
+		}
+		covered[id] = true
+		queue = append(queue, children[id]...)
+	}
+	return covered
+}
+
+func matches(categoryIDs []int, covered map[int]bool) bool {
+	for _, id := range categoryIDs {
+		if covered[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	start, _ := recurrence.MonthRange(t)
+	return start
+}
+
+// Round rounds to cents, so float sums do not show up as 12.000000001.
+func Round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,98 @@
+package budget
+
+import (
+	"testing"
+	"time"
+)
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func spend(m time.Time, amount float64, categories ...int) Spending {
+	return Spending{Month: m.AddDate(0, 0, 14), Amount: amount, CategoryIDs: categories}
+}
+
+func TestReportIncludesSubcategories(t *testing.T) {
+	// 1 Wohnen > 2 Miete, 3 Strom; 4 Freizeit
+	children := map[int][]int{1: {2, 3}}
+	budgets := []Budget{{ID: 10, CategoryID: 1, Limit: 1000, StartMonth: month(2025, 1)}}
+	spending := []Spending{
+		spend(month(2025, 3), 700, 2),
+		spend(month(2025, 3), 90.5, 3),
+		spend(month(2025, 3), 50, 4),
+		spend(month(2025, 2), 300, 2),
+	}
+	lines := Report(month(2025, 3), budgets, children, spending)
+	if len(lines) != 1 {
+		t.Fatalf("expected one line, got %+v", lines)
+	}
+	got := lines[0]
+	if got.Spent != 790.5 || got.Planned != 1000 || got.Remaining != 209.5 || got.Carryover != 0 || got.Status != StatusOK {
+		t.Fatalf("unexpected line: %+v", got)
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	tests := []struct {
+		spent float64
+		want  string
+	}{
+		{0, StatusOK},
+		{79.99, StatusOK},
+		{80, StatusWarning},
+		{100, StatusWarning},
+		{100.01, StatusExceeded},
+	}
+	for _, tt := range tests {
+		budgets := []Budget{{ID: 1, CategoryID: 1, Limit: 100, StartMonth: month(2025, 1)}}
+		lines := Report(month(2025, 1), budgets, nil, []Spending{spend(month(2025, 1), tt.spent, 1)})
+		if lines[0].Status != tt.want {
+			t.Errorf("spent %.2f: got %s, want %s", tt.spent, lines[0].Status, tt.want)
+		}
+	}
+}
+
+func TestReportRollover(t *testing.T) {
+	budgets := []Budget{
+		{ID: 1, CategoryID: 1, Limit: 100, Rollover: true, StartMonth: month(2025, 1)},
+		{ID: 2, CategoryID: 1, Limit: 100, StartMonth: month(2025, 1)},
+	}
+	spending := []Spending{
+		spend(month(2025, 1), 40, 1),  // 60 left
+		spend(month(2025, 2), 200, 1), // 160 available, overspent: nothing carried
+		spend(month(2025, 3), 70, 1),  // 30 left
+		spend(month(2025, 4), 120, 1),
+	}
+	lines := Report(month(2025, 4), budgets, nil, spending)
+	if len(lines) != 2 {
+		t.Fatalf("expected two lines, got %+v", lines)
+	}
+	if got := lines[0]; got.Carryover != 30 || got.Planned != 130 || got.Remaining != 10 || got.Status != StatusWarning {
+		t.Fatalf("unexpected rollover line: %+v", got)
+	}
+	if got := lines[1]; got.Carryover != 0 || got.Planned != 100 || got.Remaining != -20 || got.Status != StatusExceeded {
+		t.Fatalf("unexpected line without rollover: %+v", got)
+	}
+}
+
+func TestReportSkipsBudgetsStartingLater(t *testing.T) {
+	budgets := []Budget{{ID: 1, CategoryID: 1, Limit: 100, Rollover: true, StartMonth: month(2025, 5)}}
+	if lines := Report(month(2025, 4), budgets, nil, nil); len(lines) != 0 {
+		t.Fatalf("expected no lines before the start month, got %+v", lines)
+	}
+}
+
+func TestEarliestMonth(t *testing.T) {
+	budgets := []Budget{
+		{Rollover: true, StartMonth: month(2024, 11)},
+		{Rollover: false, StartMonth: month(2020, 1)},
+	}
+	if got := EarliestMonth(month(2025, 3), budgets); !got.Equal(month(2024, 11)) {
+		t.Fatalf("got %s", got)
+	}
+	budgets[0].StartMonth = month(2000, 1)
+	if got := EarliestMonth(month(2025, 3), budgets); !got.Equal(month(2015, 3)) {
+		t.Fatalf("rollover must be capped, got %s", got)
+	}
+}
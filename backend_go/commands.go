@@ -0,0 +1,231 @@
+package main
+
+import (
+	"backend_go/auth"
+	"backend_go/blobstore"
+	"backend_go/db"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/oidc/oidctest"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runCommand executes an administrative subcommand instead of starting the server.
+// It returns false if args do not name a known command.
+func runCommand(args []string) bool {
+	switch args[0] {
+	case "lockouts":
+		listLockouts()
+	case "unlock":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: backend_go unlock <email|ip>")
+			os.Exit(2)
+		}
+		unlock(args[1])
+	case "purge-deletions":
+		purgeDeletions()
+	case "migrate":
+		migrate(args[1:])
+	case "receipts":
+		if len(args) < 2 || len(args) > 3 || args[1] != "migrate" {
+			fmt.Fprintln(os.Stderr, "usage: backend_go receipts migrate [batch-size]")
+			os.Exit(2)
+		}
+		migrateReceipts(args[2:])
+	case "mock-oidc":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "usage: backend_go mock-oidc <listen-addr> <client-id>")
+			os.Exit(2)
+		}
+		runMockOIDC(args[1], args[2])
+	default:
+		return false
+	}
+	return true
+}
+
+// listLockouts prints all login throttle keys that are locked right now.
+func listLockouts() {
+	database, err := db.GetDB()
+	if err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+
+	throttles, err := dao.NewLoginThrottleDAO(database).ListLocked()
+	if err != nil {
+		log.Fatal("Error listing lockouts: ", err)
+	}
+	for _, t := range throttles {
+		fmt.Printf("%s\tfailures=%d\tlocked for %s\n", t.Key, t.Failures, time.Until(*t.LockedUntil).Round(time.Second))
+	}
+}
+
+// unlock clears the login lockout of an account (by email) or a client IP.
+func unlock(target string) {
+	database, err := db.GetDB()
+	if err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+
+	key := dao.AccountThrottleKey(target)
+	if net.ParseIP(target) != nil {
+		key = dao.IPThrottleKey(target)
+	}
+	if err := dao.NewLoginThrottleDAO(database).Clear(key); err != nil {
+		log.Fatal("Error clearing lockout: ", err)
+	}
+	event := &models.AuditEvent{Event: models.AuditLockoutCleared, Details: fmt.Sprintf(`{"key":%q,"via":"cli"}`, key)}
+	if err := dao.NewAuditDAO(database).Record(event); err != nil {
+		log.Printf("Error recording the unlock: %v", err)
+	}
+	fmt.Printf("Cleared lockout for %s\n", key)
+}
+
+// purgeDeletions deletes all accounts whose deletion grace period has ended.
+func purgeDeletions() {
+	database, err := db.GetDB()
+	if err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+
+	receiptStore, err := blobstore.FromEnv(database)
+	if err != nil {
+		log.Fatal("Error configuring the receipt store: ", err)
+	}
+
+	purged, err := dao.NewUserDAO(database, receiptStore).PurgeScheduledDeletions(time.Now())
+	if err != nil {
+		log.Fatal("Error purging deleted accounts: ", err)
+	}
+	auditDAO := dao.NewAuditDAO(database)
+	for _, id := range purged {
+		if err := auditDAO.RecordAccountPurged(id); err != nil {
+			log.Printf("Error recording purge of user %d: %v", id, err)
+		}
+	}
+	fmt.Printf("Purged %d accounts\n", len(purged))
+}
+
+// migrate runs `migrate up`, `migrate down [steps]` or `migrate status`.
+func migrate(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: backend_go migrate up | down [steps] | status")
+		os.Exit(2)
+	}
+	if len(args) == 0 || len(args) > 2 {
+		usage()
+	}
+
+	database, err := db.GetDB()
+	if err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		log.Fatal("Error loading migrations: ", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("Error migrating the database: ", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				usage()
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("Error reverting migrations: ", err)
+		}
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("Error reading the migration status: ", err)
+		}
+		for _, s := range status {
+			name := s.Name
+			if name == "" {
+				name = "(unknown to this version)"
+			}
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, name, state)
+		}
+	default:
+		usage()
+	}
+}
+
+// migrateReceipts moves receipts stored in haushaltsausgaben.receipt into the
+// configured receipt store (RECEIPT_STORE). It can be interrupted and rerun.
+func migrateReceipts(args []string) {
+	batchSize := 100
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Fprintln(os.Stderr, "usage: backend_go receipts migrate [batch-size]")
+			os.Exit(2)
+		}
+		batchSize = n
+	}
+
+	database, err := db.GetDB()
+	if err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+	receiptStore, err := blobstore.FromEnv(database)
+	if err != nil {
+		log.Fatal("Error configuring the receipt store: ", err)
+	}
+
+	moved, err := dao.NewHaushaltsausgabenDAO(database, auth.OwnerOnlyPolicy{}, receiptStore).MigrateLegacyReceipts(context.Background(), batchSize)
+	fmt.Printf("Moved %d receipts\n", moved)
+	if err != nil {
+		log.Fatal("Error moving receipts: ", err)
+	}
+}
+
+// runMockOIDC serves a mock OpenID Connect issuer for local development. It
+// signs in a fixed verified user without asking for credentials.
+func runMockOIDC(addr, clientID string) {
+	issuer, err := oidctest.NewHandler(clientID)
+	if err != nil {
+		log.Fatal("Error creating the mock issuer: ", err)
+	}
+	issuer.URL = "http://" + addr
+	if os.Getenv("MOCK_OIDC_EMAIL") != "" {
+		issuer.SetUser(oidctest.User{
+			Subject:       os.Getenv("MOCK_OIDC_EMAIL"),
+			Email:         os.Getenv("MOCK_OIDC_EMAIL"),
+			EmailVerified: true,
+			Name:          os.Getenv("MOCK_OIDC_NAME"),
+		})
+	}
+	log.Printf("Mock OIDC issuer %s for client %s", issuer.URL, clientID)
+	log.Fatal(http.ListenAndServe(addr, issuer))
+}
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAPITokenInvalid is returned for unknown, expired or revoked API tokens.
+var ErrAPITokenInvalid = errors.New("api token invalid")
+
+// lastUsedResolution limits how often the last-used time of a token is written.
+const lastUsedResolution = time.Minute
+
+// APITokenDAO manages personal API tokens.
+type APITokenDAO struct {
+	db *gorm.DB
+}
+
+// NewAPITokenDAO initializes and returns a new APITokenDAO.
+func NewAPITokenDAO(db *gorm.DB) *APITokenDAO {
+	return &APITokenDAO{db: db}
+}
+
+// Create issues a new token for the user and returns the plaintext token, which
+// is only shown once. A nil expiresAt creates a token that does not expire.
+func (dao *APITokenDAO) Create(userID int, name string, scopes []string, expiresAt *time.Time) (string, *models.APIToken, error) {
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	random, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	plain := auth.APITokenPrefix + random
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:len(auth.APITokenPrefix)+8],
+		TokenHash: auth.HashToken(plain),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	if err := dao.db.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// ListForUser returns the user's tokens that are not revoked, newest first.
+func (dao *APITokenDAO) ListForUser(userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := dao.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke revokes one token of the user. It returns gorm.ErrRecordNotFound if
+// the token does not exist or belongs to someone else.
+func (dao *APITokenDAO) Revoke(userID, id int) error {
+	res := dao.db.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every token of the user.
+func (dao *APITokenDAO) RevokeAllForUser(userID int) error {
+	return dao.db.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Authenticate looks up a presented token and records its use.
+func (dao *APITokenDAO) Authenticate(plain string) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := dao.db.Where("token_hash = ?", auth.HashToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, ErrAPITokenInvalid
+	}
+
+	// Scripts may call every few seconds, so the timestamp is only refreshed once a minute
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := dao.db.Model(&models.APIToken{}).Where("id = ?", token.ID).Update("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return &token, nil
+}
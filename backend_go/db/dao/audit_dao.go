@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"backend_go/db/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxAuditEvents limits the number of events returned by one query.
+const MaxAuditEvents = 500
+
+// AuditFilter narrows down an audit log query. Zero values do not filter.
+type AuditFilter struct {
+	Event     string
+	ActorID   int
+	SubjectID int
+	IP        string
+	Since     time.Time
+	Until     time.Time
+	BeforeID  int64 // for paging, only events older than this ID
+	Limit     int
+}
+
+// AuditDAO writes and reads the audit log. Events can only be added, never
+// changed; the table is additionally protected by a trigger.
+type AuditDAO struct {
+	db *gorm.DB
+}
+
+// NewAuditDAO initializes and returns a new AuditDAO.
+func NewAuditDAO(db *gorm.DB) *AuditDAO {
+	return &AuditDAO{db: db}
+}
+
+// Record appends an event to the log.
+func (dao *AuditDAO) Record(event *models.AuditEvent) error {
+	return dao.db.Create(event).Error
+}
+
+// RecordAccountPurged records the deletion of an account by the purge job,
+// which runs outside of any request.
+func (dao *AuditDAO) RecordAccountPurged(userID int) error {
+	return dao.Record(&models.AuditEvent{
+		Event:     models.AuditAccountDeleted,
+		SubjectID: &userID,
+		Details:   `{"reason":"grace_period_ended"}`,
+	})
+}
+
+// Query returns the matching events, newest first.
+func (dao *AuditDAO) Query(filter AuditFilter) ([]models.AuditEvent, error) {
+	query := dao.db.Model(&models.AuditEvent{})
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.SubjectID != 0 {
+		query = query.Where("subject_id = ?", filter.SubjectID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxAuditEvents {
+		limit = MaxAuditEvents
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"backend_go/db/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAuditEventsAreAppendOnly(t *testing.T) {
+	database := testDB(t)
+	audit := NewAuditDAO(database)
+	event := &models.AuditEvent{Event: models.AuditLoginFailed, IP: "append-only"}
+	if err := audit.Record(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Model(event).Update("event", models.AuditLoginSucceeded).Error; err == nil {
+		t.Error("audit event could be updated")
+	}
+	if err := database.Delete(event).Error; err == nil {
+		t.Error("audit event could be deleted")
+	}
+}
+
+func TestAuditQueryFilters(t *testing.T) {
+	audit := NewAuditDAO(testDB(t))
+	// Events cannot be deleted, so every run uses its own IP to find its events
+	ip := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	actor, subject, other := 1, 2, 3
+	events := []*models.AuditEvent{
+		{Event: models.AuditLoginFailed, IP: ip, SubjectID: &subject},
+		{Event: models.AuditLoginSucceeded, IP: ip, ActorID: &actor, SubjectID: &subject},
+		{Event: models.AuditLoginSucceeded, IP: ip, ActorID: &other, SubjectID: &other},
+	}
+	for _, event := range events {
+		if err := audit.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(filter AuditFilter) []int64 {
+		t.Helper()
+		filter.IP = ip
+		found, err := audit.Query(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, event := range found {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+	first, second, third := events[0].ID, events[1].ID, events[2].ID
+
+	for name, tc := range map[string]struct {
+		filter AuditFilter
+		want   []int64
+	}{
+		"all, newest first": {AuditFilter{}, []int64{third, second, first}},
+		"event":             {AuditFilter{Event: models.AuditLoginSucceeded}, []int64{third, second}},
+		"actor":             {AuditFilter{ActorID: actor}, []int64{second}},
+		"subject":           {AuditFilter{SubjectID: subject}, []int64{second, first}},
+		"before":            {AuditFilter{BeforeID: third}, []int64{second, first}},
+		"limit":             {AuditFilter{Limit: 2}, []int64{third, second}},
+		"since":             {AuditFilter{Since: time.Now().Add(time.Hour)}, nil},
+		"until":             {AuditFilter{Until: time.Now().Add(-time.Hour)}, nil},
+		"time range":        {AuditFilter{Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour)}, []int64{third, second, first}},
+	} {
+		if got := ids(tc.filter); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+}
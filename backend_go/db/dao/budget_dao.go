@@ -0,0 +1,156 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/budget"
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidBudget kennzeichnet Eingabefehler bei Budgets
+	ErrInvalidBudget = errors.New("invalid budget")
+	// ErrBudgetExists bedeutet, dass es für die Kategorie schon ein Budget gibt
+	ErrBudgetExists = errors.New("budget already exists for this category")
+)
+
+// BudgetDAO verwaltet die Monatsbudgets eines Benutzers. Fremde Budgets gelten
+// als nicht vorhanden (gorm.ErrRecordNotFound).
+type BudgetDAO struct {
+	db       *gorm.DB
+	expenses *HaushaltsausgabenDAO
+}
+
+// NewBudgetDAO Konstruktor für das DAO; die Ausgaben liefert expenses, damit der
+// Report dieselbe Monatslogik wie die Monatsansicht verwendet
+func NewBudgetDAO(db *gorm.DB, expenses *HaushaltsausgabenDAO) *BudgetDAO {
+	return &BudgetDAO{db: db, expenses: expenses}
+}
+
+// ListBudgets liefert alle Budgets des Benutzers
+func (dao *BudgetDAO) ListBudgets(userID int) ([]models.Budget, error) {
+	var budgets []models.Budget
+	if err := dao.db.Where("user_id = ?", userID).Order("id").Find(&budgets).Error; err != nil {
+		log.Printf("[DAO.ListBudgets] ERROR fetching budgets of UserID %d: %v", userID, err)
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// CreateBudget legt das Budget für eine eigene Kategorie an; es gilt ab startMonth
+func (dao *BudgetDAO) CreateBudget(userID, categoryID int, amount float64, rollover bool, startMonth time.Time) (*models.Budget, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	}
+	startMonth, _ = recurrence.MonthRange(startMonth)
+	budget := models.Budget{UserID: userID, CategoryID: categoryID, Amount: amount, Rollover: rollover, StartMonth: startMonth}
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCategory(tx, userID, categoryID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown category %d", ErrInvalidBudget, categoryID)
+			}
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Budget{}).Where("user_id = ? AND category_id = ?", userID, categoryID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBudgetExists
+		}
+		return tx.Create(&budget).Error
+	})
+	if err != nil {
+		log.Printf("[DAO.CreateBudget] ERROR creating budget for category ID %d of UserID %d: %v", categoryID, userID, err)
+		return nil, err
+	}
+	log.Printf("[DAO.CreateBudget] Created budget ID %d for UserID %d", budget.ID, userID)
+	return &budget, nil
+}
+
+// UpdateBudget ändert Betrag, Rollover und Startmonat eines Budgets; ohne
+// startMonth bleibt der bisherige
+func (dao *BudgetDAO) UpdateBudget(userID, id int, amount float64, rollover bool, startMonth *time.Time) (*models.Budget, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	}
+	var budget models.Budget
+	if err := dao.db.Where("user_id = ?", userID).First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	budget.Amount, budget.Rollover = amount, rollover
+	if startMonth != nil {
+		budget.StartMonth, _ = recurrence.MonthRange(*startMonth)
+	}
+	if err := dao.db.Save(&budget).Error; err != nil {
+		log.Printf("[DAO.UpdateBudget] ERROR updating budget ID %d of UserID %d: %v", id, userID, err)
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// DeleteBudget löscht ein Budget des Benutzers
+func (dao *BudgetDAO) DeleteBudget(userID, id int) error {
+	tx := dao.db.Where("user_id = ?", userID).Delete(&models.Budget{}, id)
+	if tx.Error != nil {
+		log.Printf("[DAO.DeleteBudget] ERROR deleting budget ID %d of UserID %d: %v", id, userID, tx.Error)
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Report berechnet die Budgets des angemeldeten Benutzers für einen Monat. Die
+// Ausgaben kommen aus GetOccurrences, zählen also genau wie in der Monatsansicht;
+// für den Rollover werden die Vormonate ab dem frühesten Startmonat mitgelesen.
+func (dao *BudgetDAO) Report(p auth.Principal, month time.Time) ([]budget.Line, error) {
+	budgets, err := dao.ListBudgets(p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return []budget.Line{}, nil
+	}
+	var categories []models.Category
+	if err := dao.db.Where("user_id = ?", p.UserID).Find(&categories).Error; err != nil {
+		log.Printf("[DAO.Report] ERROR fetching categories of UserID %d: %v", p.UserID, err)
+		return nil, err
+	}
+	children := map[int][]int{}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	input := make([]budget.Budget, 0, len(budgets))
+	for _, b := range budgets {
+		input = append(input, budget.Budget{ID: b.ID, CategoryID: b.CategoryID, Limit: b.Amount, Rollover: b.Rollover, StartMonth: b.StartMonth})
+	}
+
+	_, to := recurrence.MonthRange(month)
+	occurrences, err := dao.expenses.GetOccurrences(p, p.UserID, budget.EarliestMonth(month, input), to)
+	if err != nil {
+		return nil, err
+	}
+	spending := make([]budget.Spending, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		if len(occurrence.Expense.Categories) == 0 {
+			continue
+		}
+		ids := make([]int, 0, len(occurrence.Expense.Categories))
+		for _, category := range occurrence.Expense.Categories {
+			ids = append(ids, category.ID)
+		}
+		spending = append(spending, budget.Spending{Month: occurrence.DueDate, Amount: occurrence.Expense.DueAmount(), CategoryIDs: ids})
+	}
+	return budget.Report(month, input, children, spending), nil
+}
@@ -0,0 +1,376 @@
+package dao
+
+import (
+	"backend_go/db/models"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidCategory kennzeichnet Eingabefehler bei Kategorien und Tags
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrCategoryExists bedeutet, dass es auf derselben Ebene schon eine Kategorie dieses Namens gibt
+	ErrCategoryExists = errors.New("category already exists")
+	// ErrTagExists bedeutet, dass der Benutzer schon einen Tag dieses Namens hat
+	ErrTagExists = errors.New("tag already exists")
+)
+
+// Längen wie in Migration 0007_categories_tags
+const (
+	maxCategoryNameLength = 100
+	maxTagNameLength      = 50
+)
+
+// CategoryDAO verwaltet die Kategorien und Tags eines Benutzers. Fremde Einträge
+// gelten als nicht vorhanden (gorm.ErrRecordNotFound).
+type CategoryDAO struct {
+	db *gorm.DB
+}
+
+// NewCategoryDAO Konstruktor für das DAO
+func NewCategoryDAO(db *gorm.DB) *CategoryDAO {
+	return &CategoryDAO{db: db}
+}
+
+// --- Kategorien ---
+
+// ListCategories liefert alle Kategorien des Benutzers, sortiert nach Name
+func (dao *CategoryDAO) ListCategories(userID int) ([]models.Category, error) {
+	var categories []models.Category
+	if err := dao.db.Where("user_id = ?", userID).Order("lower(name), id").Find(&categories).Error; err != nil {
+		log.Printf("[DAO.ListCategories] ERROR fetching categories of UserID %d: %v", userID, err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategory holt eine Kategorie des Benutzers
+func (dao *CategoryDAO) GetCategory(userID, id int) (*models.Category, error) {
+	var category models.Category
+	if err := dao.db.Where("user_id = ?", userID).First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// CreateCategory legt eine Kategorie an, ohne parentID auf oberster Ebene
+func (dao *CategoryDAO) CreateCategory(userID int, name string, parentID *int) (*models.Category, error) {
+	name, err := normalizeName(name, maxCategoryNameLength)
+	if err != nil {
+		return nil, err
+	}
+	category := models.Category{UserID: userID, ParentID: parentID, Name: name}
+	err = dao.db.Transaction(func(tx *gorm.DB) error {
+		if parentID != nil {
+			if _, err := lockCategory(tx, userID, *parentID); err != nil {
+				return parentError(err)
+			}
+		}
+		if err := checkSiblingName(tx, userID, parentID, name, 0); err != nil {
+			return err
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		log.Printf("[DAO.CreateCategory] ERROR creating category %q for UserID %d: %v", name, userID, err)
+		return nil, err
+	}
+	log.Printf("[DAO.CreateCategory] Created category ID %d for UserID %d", category.ID, userID)
+	return &category, nil
+}
+
+// UpdateCategory benennt eine Kategorie um und/oder hängt sie um. Eine Kategorie
+// kann nicht unter sich selbst oder eine ihrer Unterkategorien gehängt werden.
+func (dao *CategoryDAO) UpdateCategory(userID, id int, name string, parentID *int) (*models.Category, error) {
+	name, err := normalizeName(name, maxCategoryNameLength)
+	if err != nil {
+		return nil, err
+	}
+	var category *models.Category
+	err = dao.db.Transaction(func(tx *gorm.DB) error {
+		if category, err = lockCategory(tx, userID, id); err != nil {
+			return err
+		}
+		if parentID != nil {
+			if err := checkNoCycle(tx, userID, id, *parentID); err != nil {
+				return err
+			}
+		}
+		if err := checkSiblingName(tx, userID, parentID, name, id); err != nil {
+			return err
+		}
+		category.Name, category.ParentID = name, parentID
+		return tx.Model(&models.Category{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"name": name, "parent_id": parentID}).Error
+	})
+	if err != nil {
+		log.Printf("[DAO.UpdateCategory] ERROR updating category ID %d of UserID %d: %v", id, userID, err)
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory löscht eine Kategorie. Unterkategorien rücken eine Ebene nach
+// oben, Ausgaben verlieren nur die Zuordnung zu dieser Kategorie.
+func (dao *CategoryDAO) DeleteCategory(userID, id int) error {
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		category, err := lockCategory(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		var children []models.Category
+		if err := tx.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := checkSiblingName(tx, userID, category.ParentID, child.Name, id); err != nil {
+				return fmt.Errorf("%w: subcategory %q would clash with an existing category", ErrCategoryExists, child.Name)
+			}
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{}, id).Error
+	})
+	if err != nil {
+		log.Printf("[DAO.DeleteCategory] ERROR deleting category ID %d of UserID %d: %v", id, userID, err)
+		return err
+	}
+	log.Printf("[DAO.DeleteCategory] Deleted category ID %d of UserID %d", id, userID)
+	return nil
+}
+
+func lockCategory(tx *gorm.DB, userID, id int) (*models.Category, error) {
+	var category models.Category
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// parentError meldet eine unbekannte Oberkategorie als Eingabefehler statt als 404
+func parentError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: parent category not found", ErrInvalidCategory)
+	}
+	return err
+}
+
+// checkNoCycle geht von der neuen Oberkategorie bis zur Wurzel und darf dabei id
+// nicht begegnen
+func checkNoCycle(tx *gorm.DB, userID, id, parentID int) error {
+	for current := &parentID; current != nil; {
+		if *current == id {
+			return fmt.Errorf("%w: a category cannot be moved below itself", ErrInvalidCategory)
+		}
+		parent, err := lockCategory(tx, userID, *current)
+		if err != nil {
+			return parentError(err)
+		}
+		current = parent.ParentID
+	}
+	return nil
+}
+
+// checkSiblingName prüft die Eindeutigkeit des Namens unter parentID; exceptID
+// nimmt die Kategorie selbst (beim Umbenennen) aus
+func checkSiblingName(tx *gorm.DB, userID int, parentID *int, name string, exceptID int) error {
+	query := tx.Model(&models.Category{}).Where("user_id = ? AND lower(name) = lower(?) AND id <> ?", userID, name, exceptID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryExists
+	}
+	return nil
+}
+
+// --- Tags ---
+
+// ListTags liefert alle Tags des Benutzers, sortiert nach Name
+func (dao *CategoryDAO) ListTags(userID int) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := dao.db.Where("user_id = ?", userID).Order("lower(name), id").Find(&tags).Error; err != nil {
+		log.Printf("[DAO.ListTags] ERROR fetching tags of UserID %d: %v", userID, err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+// CreateTag legt einen Tag an. Meist entstehen Tags aber beim Zuordnen zu einer Ausgabe.
+func (dao *CategoryDAO) CreateTag(userID int, name string) (*models.Tag, error) {
+	name, err := normalizeName(name, maxTagNameLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTagName(dao.db, userID, name, 0); err != nil {
+		return nil, err
+	}
+	tag := models.Tag{UserID: userID, Name: name}
+	if err := dao.db.Create(&tag).Error; err != nil {
+		log.Printf("[DAO.CreateTag] ERROR creating tag %q for UserID %d: %v", name, userID, err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// RenameTag ändert den Namen eines Tags an allen Ausgaben
+func (dao *CategoryDAO) RenameTag(userID, id int, name string) (*models.Tag, error) {
+	name, err := normalizeName(name, maxTagNameLength)
+	if err != nil {
+		return nil, err
+	}
+	var tag models.Tag
+	if err := dao.db.Where("user_id = ?", userID).First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	if err := checkTagName(dao.db, userID, name, id); err != nil {
+		return nil, err
+	}
+	if err := dao.db.Model(&tag).Update("name", name).Error; err != nil {
+		log.Printf("[DAO.RenameTag] ERROR renaming tag ID %d: %v", id, err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag löscht einen Tag und entfernt ihn von allen Ausgaben
+func (dao *CategoryDAO) DeleteTag(userID, id int) error {
+	tx := dao.db.Where("user_id = ?", userID).Delete(&models.Tag{}, id)
+	if tx.Error != nil {
+		log.Printf("[DAO.DeleteTag] ERROR deleting tag ID %d: %v", id, tx.Error)
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func checkTagName(db *gorm.DB, userID int, name string, exceptID int) error {
+	var count int64
+	if err := db.Model(&models.Tag{}).Where("user_id = ? AND lower(name) = lower(?) AND id <> ?", userID, name, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTagExists
+	}
+	return nil
+}
+
+// --- Zuordnung zu Ausgaben ---
+
+// ExpenseLabels sind die Kategorien und Tags einer Ausgabe. Ein nil-Slice lässt
+// die bisherige Zuordnung unverändert, ein leerer Slice entfernt sie.
+type ExpenseLabels struct {
+	CategoryIDs []int
+	Tags        []string
+}
+
+// setExpenseLabels ersetzt die Zuordnungen einer Ausgabe. Kategorien müssen dem
+// Besitzer der Ausgabe gehören, unbekannte Tags werden für ihn angelegt.
+func setExpenseLabels(tx *gorm.DB, expenseID, ownerID int, labels ExpenseLabels) error {
+	if labels.CategoryIDs != nil {
+		ids := uniqueInts(labels.CategoryIDs)
+		var count int64
+		if len(ids) > 0 {
+			if err := tx.Model(&models.Category{}).Where("user_id = ? AND id IN ?", ownerID, ids).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(ids) {
+				return fmt.Errorf("%w: unknown category", ErrInvalidCategory)
+			}
+		}
+		if err := tx.Exec("DELETE FROM expense_categories WHERE expense_id = ?", expenseID).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.Exec("INSERT INTO expense_categories (expense_id, category_id) VALUES (?, ?)", expenseID, id).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if labels.Tags != nil {
+		tagIDs, err := ensureTags(tx, ownerID, labels.Tags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM expense_tags WHERE expense_id = ?", expenseID).Error; err != nil {
+			return err
+		}
+		for _, id := range tagIDs {
+			if err := tx.Exec("INSERT INTO expense_tags (expense_id, tag_id) VALUES (?, ?)", expenseID, id).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureTags liefert die IDs der Tags und legt fehlende an
+func ensureTags(tx *gorm.DB, userID int, names []string) ([]int, error) {
+	seen := map[string]bool{}
+	var lowered []string
+	for _, name := range names {
+		name, err := normalizeName(name, maxTagNameLength)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		lowered = append(lowered, key)
+		if err := tx.Exec("INSERT INTO tags (user_id, name) VALUES (?, ?) ON CONFLICT (user_id, lower(name)) DO NOTHING", userID, name).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(lowered) == 0 {
+		return nil, nil
+	}
+	var ids []int
+	if err := tx.Model(&models.Tag{}).Where("user_id = ? AND lower(name) IN ?", userID, lowered).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// normalizeName entfernt überflüssige Leerzeichen und prüft die Länge
+func normalizeName(name string, maxLength int) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name must not be empty", ErrInvalidCategory)
+	}
+	if utf8.RuneCountInString(name) > maxLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidCategory, maxLength)
+	}
+	return name, nil
+}
+
+func uniqueInts(values []int) []int {
+	seen := map[int]bool{}
+	unique := []int{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Ints(unique)
+	return unique
+}
@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrVerificationTokenInvalid is returned for unknown, expired or already used verification tokens.
+var ErrVerificationTokenInvalid = errors.New("verification token invalid")
+
+// EmailVerificationDAO stores hashed email verification tokens.
+type EmailVerificationDAO struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationDAO initializes and returns a new EmailVerificationDAO.
+func NewEmailVerificationDAO(db *gorm.DB) *EmailVerificationDAO {
+	return &EmailVerificationDAO{db: db}
+}
+
+// Create stores a new verification token for the user and returns the plaintext token.
+func (dao *EmailVerificationDAO) Create(userID int, ttl time.Duration) (string, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	token := &models.EmailVerificationToken{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := dao.db.Create(token).Error; err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// Verify consumes the token and marks the user's email address as verified.
+func (dao *EmailVerificationDAO) Verify(plain string) (int, error) {
+	var userID int
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		var token models.EmailVerificationToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(plain), time.Now()).
+			First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		res := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVerificationTokenInvalid
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", token.UserID).Update("verified_at", now).Error; err != nil {
+			return err
+		}
+		userID = token.UserID
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -1,69 +1,122 @@
 package dao
 
 import (
+	"backend_go/auth"
+	"backend_go/blobstore"
 	"backend_go/db/models" // Stelle sicher, dass dieser Importpfad korrekt ist
-	"log"                  // Importiere das log-Paket
+	"backend_go/recurrence"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log" // Importiere das log-Paket
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// HaushaltsausgabenDAO Struktur
+// ErrInvalidRecurrence kennzeichnet ungültige Wiederholungsregeln (Eingabefehler)
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// ErrVersionMismatch bedeutet, dass der Eintrag seit dem Lesen geändert wurde
+var ErrVersionMismatch = errors.New("expense was modified in the meantime")
+
+// HaushaltsausgabenDAO Struktur. Alle Methoden arbeiten im Namen eines Principals;
+// die Policy entscheidet, welche Einträge dieser sehen und ändern darf.
+// Belege liegen im BlobStore, die Tabelle hält nur Schlüssel und Metadaten.
 type HaushaltsausgabenDAO struct {
-	db *gorm.DB
+	db     *gorm.DB
+	policy auth.ExpensePolicy
+	blobs  blobstore.BlobStore
 }
 
 // NewHaushaltsausgabenDAO Konstruktor für das DAO
-func NewHaushaltsausgabenDAO(db *gorm.DB) *HaushaltsausgabenDAO {
+func NewHaushaltsausgabenDAO(db *gorm.DB, policy auth.ExpensePolicy, blobs blobstore.BlobStore) *HaushaltsausgabenDAO {
 	// Optional: Logge die erfolgreiche DAO-Initialisierung
 	log.Println("HaushaltsausgabenDAO initialized successfully.")
-	return &HaushaltsausgabenDAO{db: db}
+	return &HaushaltsausgabenDAO{db: db, policy: policy, blobs: blobs}
+}
+
+// withoutReceipt lässt Beleg und Vorschaubild weg; Listen brauchen nur die Metadaten
+func withoutReceipt(db *gorm.DB) *gorm.DB {
+	return db.Omit("receipt", "receipt_thumbnail")
+}
+
+// withLabels lädt Kategorien und Tags der Ausgaben mit
+func withLabels(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("lower(name)") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("lower(name)") })
+}
+
+// ExpenseFilter schränkt Listen ein. Eine Kategorie schließt ihre Unterkategorien
+// ein; mehrere Kategorien werden mit ODER, mehrere Tags mit UND verknüpft.
+type ExpenseFilter struct {
+	CategoryIDs []int
+	Tags        []string
+}
+
+func (f ExpenseFilter) scope(db *gorm.DB) *gorm.DB {
+	if len(f.CategoryIDs) > 0 {
+		db = db.Where(`id IN (SELECT expense_id FROM expense_categories WHERE category_id IN (
+			WITH RECURSIVE sub AS (
+				SELECT id FROM categories WHERE id IN ?
+				UNION SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+			) SELECT id FROM sub))`, f.CategoryIDs)
+	}
+	for _, tag := range f.Tags {
+		db = db.Where(`id IN (SELECT et.expense_id FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+			WHERE lower(t.name) = lower(?))`, strings.TrimSpace(tag))
+	}
+	return db
+}
+
+// scoped schränkt eine Abfrage auf die Einträge ein, die der Principal lesen darf
+func (dao *HaushaltsausgabenDAO) scoped(p auth.Principal) *gorm.DB {
+	return dao.db.Where("userid IN ?", dao.policy.ReadableOwners(p))
 }
 
 // --- Konstanten (bleiben unverändert, aber ungenutzt von GORM Methoden) ---
 const (
 	CreateHaushaltsausgabenQuery = `
     INSERT INTO haushaltsausgaben
-    (description, valuetotal, valuerate, creditstart, creditend, type, user_id, created_at, changed_at, faelligkeitstag, zahldatum)
+    (description, valuetotal, valuerate, creditstart, creditend, type, userid, created_at, changed_at, faelligkeitstag, zahldatum)
     VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, ?, ?)
     RETURNING id`
 
 	UpdateHaushaltsausgabenQuery = `
     UPDATE haushaltsausgaben
-    SET description = ?, valuetotal = ?, valuerate = ?, creditstart = ?, creditend = ?, type = ?, user_id = ?, changed_at = CURRENT_TIMESTAMP, faelligkeitstag = ?, zahldatum = ?
+    SET description = ?, valuetotal = ?, valuerate = ?, creditstart = ?, creditend = ?, type = ?, userid = ?, changed_at = CURRENT_TIMESTAMP, faelligkeitstag = ?, zahldatum = ?
     WHERE id = ?`
 
-	ReadHaushaltsausgabenQuery = `SELECT id, description, valuetotal, valuerate, creditstart, creditend, type, user_id, created_at, changed_at, faelligkeitstag FROM haushaltsausgaben`
+	ReadHaushaltsausgabenQuery = `SELECT id, description, valuetotal, valuerate, creditstart, creditend, type, userid, created_at, changed_at, faelligkeitstag FROM haushaltsausgaben`
 
 	DeleteHaushaltsausgabenQuery = `DELETE FROM haushaltsausgaben WHERE id = ?`
-
-	ExpensesQuery = `
-    SELECT *
-    FROM haushaltsausgaben
-    WHERE userid = $1
-    AND (
-        type = 'monthlycosts'
-        OR
-        (type = 'credit'
-            AND TO_CHAR(creditend::date, 'YYYY-MM') >= $2
-            AND TO_CHAR(creditstart::date, 'YYYY-MM') <= $3
-        )
-        OR
-        (type = 'allelse'
-            AND TO_CHAR(created_at::date, 'YYYY-MM') = $4
-        )
-        OR
-        (type = 'invoice'
-            AND TO_CHAR(zahldatum::date, 'YYYY-MM') = $4
-        )
-    )
-`
 )
 
 // --- CRUD Methoden mit Logging ---
 
-// Create erstellt einen neuen Haushaltsausgaben-Eintrag
-func (dao *HaushaltsausgabenDAO) Create(description string, valuetotal, valuerate float64, creditstart, creditend time.Time, typ string, userID int, faelligkeitstag string, zahldatum time.Time) (*models.Haushaltsausgaben, error) {
+// Create erstellt einen neuen Haushaltsausgaben-Eintrag, Besitzer ist immer der Principal
+func (dao *HaushaltsausgabenDAO) Create(p auth.Principal, description string, valuetotal, valuerate float64, creditstart, creditend time.Time, typ string, faelligkeitstag string, zahldatum time.Time, rule *recurrence.Rule, labels ExpenseLabels) (*models.Haushaltsausgaben, error) {
+	userID := p.UserID
+	if !dao.policy.CanWrite(p, userID) {
+		return nil, auth.ErrForbidden
+	}
+	now := time.Now().Truncate(time.Microsecond) // Genauigkeit von Postgres, siehe Version()
+	if rule == nil {
+		legacy := models.LegacyRecurrence(typ, now, creditstart, creditend, zahldatum)
+		rule = &legacy
+	}
+	if err := validateRecurrence(*rule); err != nil {
+		return nil, err
+	}
+
 	// --- Logging: Eingangsparameter ---
 	log.Printf("[DAO.Create] Received parameters: UserID=%d, Type=%s, ValueTotal=%.2f, Description=%s, Faelligkeitstag=%s, CreditStart=%v, CreditEnd=%v, Zahldatum=%v, ValueRate=%.2f",
 		userID, typ, valuetotal, description, faelligkeitstag, creditstart, creditend, zahldatum, valuerate)
@@ -77,12 +130,13 @@ func (dao *HaushaltsausgabenDAO) Create(description string, valuetotal, valuerat
 		CreditEnd:       creditend,
 		Type:            typ,
 		UserID:          userID,
-		CreatedAt:       time.Now(), // Explizit gesetzt, auch wenn autoCreateTime aktiv sein könnte
-		ChangedAt:       time.Now(), // Explizit gesetzt, auch wenn autoUpdateTime aktiv sein könnte
+		CreatedAt:       now, // Explizit gesetzt, auch wenn autoCreateTime aktiv sein könnte
+		ChangedAt:       now, // Explizit gesetzt, auch wenn autoUpdateTime aktiv sein könnte
 		Faelligkeitstag: faelligkeitstag,
 		Zahldatum:       zahldatum,
 		// Receipt wird hier nicht übergeben, bleibt also leer/nil
 	}
+	expense.SetRecurrence(*rule)
 
 	// --- Logging: Objekt vor dem Speichern ---
 	log.Printf("[DAO.Create] Attempting to create expense object: %+v", expense)
@@ -90,7 +144,16 @@ func (dao *HaushaltsausgabenDAO) Create(description string, valuetotal, valuerat
 	// Create the entry in the database using GORM
 	// --- Logging: Vor dem DB-Aufruf ---
 	log.Println("[DAO.Create] Calling db.Create()...")
-	if err := dao.db.Create(&expense).Error; err != nil {
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		if err := setExpenseLabels(tx, expense.ID, userID, labels); err != nil {
+			return err
+		}
+		return loadLabels(tx, &expense)
+	})
+	if err != nil {
 		// --- Logging: Fehler beim Speichern ---
 		log.Printf("[DAO.Create] ERROR creating expense in DB: %v", err)
 		return nil, err // Fehler zurückgeben
@@ -104,11 +167,11 @@ func (dao *HaushaltsausgabenDAO) Create(description string, valuetotal, valuerat
 	return &expense, nil
 }
 
-// GetAll holt alle Haushaltsausgaben
-func (dao *HaushaltsausgabenDAO) GetAll() ([]models.Haushaltsausgaben, error) {
-	log.Println("[DAO.GetAll] Fetching all expenses...")
+// GetAll holt alle Haushaltsausgaben, die der Principal lesen darf
+func (dao *HaushaltsausgabenDAO) GetAll(p auth.Principal, filter ExpenseFilter) ([]models.Haushaltsausgaben, error) {
+	log.Printf("[DAO.GetAll] Fetching all expenses readable by UserID %d...", p.UserID)
 	var expenses []models.Haushaltsausgaben
-	if err := dao.db.Find(&expenses).Error; err != nil {
+	if err := dao.scoped(p).Scopes(withoutReceipt, withLabels, filter.scope).Find(&expenses).Error; err != nil {
 		log.Printf("[DAO.GetAll] ERROR fetching expenses: %v", err)
 		return nil, err
 	}
@@ -117,12 +180,16 @@ func (dao *HaushaltsausgabenDAO) GetAll() ([]models.Haushaltsausgaben, error) {
 }
 
 // GetByUserID holt Ausgaben für eine bestimmte UserID
-func (dao *HaushaltsausgabenDAO) GetByUserID(userID int) ([]models.Haushaltsausgaben, error) {
+func (dao *HaushaltsausgabenDAO) GetByUserID(p auth.Principal, userID int, filter ExpenseFilter) ([]models.Haushaltsausgaben, error) {
 	log.Printf("[DAO.GetByUserID] Fetching expenses for UserID: %d...", userID)
+	if !auth.CanRead(dao.policy, p, userID) {
+		log.Printf("[DAO.GetByUserID] DENIED: UserID %d may not read expenses of UserID %d", p.UserID, userID)
+		return nil, auth.ErrForbidden
+	}
 	var expenses []models.Haushaltsausgaben
 	// Annahme: GORM mappt das Feld 'UserID' im Struct auf die Spalte 'userid' in der DB
 	// dank des `gorm:"column:userid"` Tags im Model. Sicherer ist es, explizit zu sein:
-	if err := dao.db.Where("userid = ?", userID).Find(&expenses).Error; err != nil {
+	if err := dao.db.Scopes(withoutReceipt, withLabels, filter.scope).Where("userid = ?", userID).Find(&expenses).Error; err != nil {
 		log.Printf("[DAO.GetByUserID] ERROR fetching expenses for UserID %d: %v", userID, err)
 		return nil, err
 	}
@@ -130,12 +197,33 @@ func (dao *HaushaltsausgabenDAO) GetByUserID(userID int) ([]models.Haushaltsausg
 	return expenses, nil
 }
 
-// GetByID holt eine einzelne Ausgabe anhand ihrer ID
-func (dao *HaushaltsausgabenDAO) GetByID(id int) (*models.Haushaltsausgaben, error) {
+// GetByUserIDWithReceipts holt Ausgaben samt Belegen, z.B. für den Datenexport
+func (dao *HaushaltsausgabenDAO) GetByUserIDWithReceipts(p auth.Principal, userID int) ([]models.Haushaltsausgaben, error) {
+	log.Printf("[DAO.GetByUserIDWithReceipts] Fetching expenses with receipts for UserID: %d...", userID)
+	if !auth.CanRead(dao.policy, p, userID) {
+		log.Printf("[DAO.GetByUserIDWithReceipts] DENIED: UserID %d may not read expenses of UserID %d", p.UserID, userID)
+		return nil, auth.ErrForbidden
+	}
+	var expenses []models.Haushaltsausgaben
+	if err := dao.db.Omit("receipt_thumbnail").Scopes(withLabels).Where("userid = ?", userID).Find(&expenses).Error; err != nil {
+		log.Printf("[DAO.GetByUserIDWithReceipts] ERROR fetching expenses for UserID %d: %v", userID, err)
+		return nil, err
+	}
+	for i := range expenses {
+		if err := dao.loadReceipt(&expenses[i]); err != nil {
+			return nil, err
+		}
+	}
+	return expenses, nil
+}
+
+// GetByID holt eine einzelne Ausgabe anhand ihrer ID.
+// Einträge, die der Principal nicht lesen darf, gelten als nicht vorhanden.
+func (dao *HaushaltsausgabenDAO) GetByID(p auth.Principal, id int) (*models.Haushaltsausgaben, error) {
 	log.Printf("[DAO.GetByID] Fetching expense by ID: %d...", id)
 	var expense models.Haushaltsausgaben
 	// First() findet den ersten passenden Eintrag oder gibt gorm.ErrRecordNotFound zurück
-	if err := dao.db.First(&expense, id).Error; err != nil {
+	if err := dao.scoped(p).Scopes(withoutReceipt, withLabels).First(&expense, id).Error; err != nil {
 		log.Printf("[DAO.GetByID] ERROR fetching expense ID %d: %v", id, err)
 		return nil, err
 	}
@@ -144,10 +232,27 @@ func (dao *HaushaltsausgabenDAO) GetByID(id int) (*models.Haushaltsausgaben, err
 }
 
 // Update modifiziert einen bestehenden Eintrag
-func (dao *HaushaltsausgabenDAO) Update(id int, description string, valuetotal, valuerate float64, creditstart, creditend time.Time, typ string, userID int, faelligkeitstag string, receipt []byte) error {
+func (dao *HaushaltsausgabenDAO) Update(p auth.Principal, id int, description string, valuetotal, valuerate float64, creditstart, creditend time.Time, typ string, faelligkeitstag string, rule *recurrence.Rule, labels ExpenseLabels) error {
 	// --- Logging: Eingangsparameter für Update ---
-	// Hinweis: userID sollte normalerweise nicht über ein Update geändert werden. Typ auch selten.
-	log.Printf("[DAO.Update] Attempting to update expense ID: %d (Data provided: UserID=%d, Type=%s, ValueTotal=%.2f, ...)", id, userID, typ, valuetotal)
+	// Hinweis: Der Besitzer wird über ein Update nie geändert. Typ auch selten.
+	log.Printf("[DAO.Update] Attempting to update expense ID: %d (Principal=%d, Type=%s, ValueTotal=%.2f, ...)", id, p.UserID, typ, valuetotal)
+
+	existing, err := dao.authorizeWrite(p, id)
+	if err != nil {
+		return err
+	}
+	// Ohne Regel bleibt eine ausdrücklich gesetzte Regel bestehen, wie bei den
+	// Kategorien und Tags. Folgt sie noch dem Typ, wird sie aus den neuen Werten
+	// abgeleitet, sonst änderten z.B. ein neues creditend oder ein neuer Typ nichts.
+	if rule == nil && existing.FollowsLegacyRecurrence() {
+		legacy := models.LegacyRecurrence(typ, existing.CreatedAt, creditstart, creditend, existing.Zahldatum)
+		rule = &legacy
+	}
+	if rule != nil {
+		if err := validateRecurrence(*rule); err != nil {
+			return err
+		}
+	}
 
 	// Verwende eine Map für Updates, um GORM explizit zu sagen, welche Spalten aktualisiert werden sollen.
 	// Die Schlüssel der Map sollten den Spaltennamen in der Datenbank entsprechen.
@@ -159,9 +264,17 @@ func (dao *HaushaltsausgabenDAO) Update(id int, description string, valuetotal,
 		"creditend":   creditend,
 		"type":        typ,
 		// "userid":          userID, // UserID sollte normalerweise nicht geändert werden!
-		"changed_at":      time.Now(), // Immer aktualisieren
+		"changed_at":      time.Now().Truncate(time.Microsecond), // Immer aktualisieren
 		"faelligkeitstag": faelligkeitstag,
-		// "receipt":         receipt, // Receipt-Handling ggf. separat/anders
+		// Belege laufen über die eigenen Receipt-Endpunkte
+	}
+	if rule != nil {
+		var withRule models.Haushaltsausgaben
+		withRule.SetRecurrence(*rule)
+		updates["recurrence"] = withRule.Recurrence
+		updates["recurrence_interval"] = withRule.RecurrenceInterval
+		updates["recurrence_start"] = withRule.RecurrenceStart
+		updates["recurrence_end"] = withRule.RecurrenceEnd
 	}
 	// Optional: Entferne Zero Values aus der Map, falls diese Felder nicht explizit
 	// auf ihren Nullwert gesetzt werden sollen, wenn sie im Input fehlen.
@@ -174,8 +287,15 @@ func (dao *HaushaltsausgabenDAO) Update(id int, description string, valuetotal,
 	// --- Logging: Vor DB-Aufruf ---
 	log.Println("[DAO.Update] Calling db.Model().Where().Updates()...")
 	// Wichtig: Model(&models.Haushaltsausgaben{}) gibt GORM den Tabellenkontext
-	tx := dao.db.Model(&models.Haushaltsausgaben{}).Where("id = ?", id).Updates(updates)
-	if err := tx.Error; err != nil {
+	var tx *gorm.DB
+	err = dao.db.Transaction(func(db *gorm.DB) error {
+		tx = db.Model(&models.Haushaltsausgaben{}).Where("id = ? AND userid = ?", id, existing.UserID).Updates(updates)
+		if tx.Error != nil {
+			return tx.Error
+		}
+		return setExpenseLabels(db, id, existing.UserID, labels)
+	})
+	if err != nil {
 		// --- Logging: Fehler beim Update ---
 		log.Printf("[DAO.Update] ERROR updating expense ID %d: %v", id, err)
 		return err
@@ -194,17 +314,85 @@ func (dao *HaushaltsausgabenDAO) Update(id int, description string, valuetotal,
 	return nil
 }
 
+// Patch ändert einen Eintrag nur, wenn er noch die erwartete Version hat. apply bekommt
+// den gesperrten aktuellen Stand samt Kategorien und Tags und ändert die gewünschten
+// Felder; Besitzer, ID und Beleg werden danach nie übernommen. Zurück kommt der neue Stand.
+func (dao *HaushaltsausgabenDAO) Patch(p auth.Principal, id int, version string, apply func(*models.Haushaltsausgaben) error) (*models.Haushaltsausgaben, error) {
+	log.Printf("[DAO.Patch] Attempting to patch expense ID: %d (Principal=%d, Version=%s)", id, p.UserID, version)
+	if _, err := dao.authorizeWrite(p, id); err != nil {
+		return nil, err
+	}
+
+	var expense models.Haushaltsausgaben
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		// Zeile sperren, damit zwischen Versionsvergleich und Update niemand dazwischenkommt
+		if err := tx.Scopes(withoutReceipt).Clauses(clause.Locking{Strength: "UPDATE"}).First(&expense, id).Error; err != nil {
+			return err
+		}
+		if err := loadLabels(tx, &expense); err != nil {
+			return err
+		}
+		if expense.Version() != version {
+			log.Printf("[DAO.Patch] CONFLICT: expense ID %d has version %s, client sent %s", id, expense.Version(), version)
+			return ErrVersionMismatch
+		}
+
+		if err := apply(&expense); err != nil {
+			return err
+		}
+		if err := validateRecurrence(expense.RecurrenceRule()); err != nil {
+			return err
+		}
+
+		// Mikrosekunden wie in Postgres, damit die neue Version direkt der gespeicherten entspricht
+		expense.ChangedAt = time.Now().Truncate(time.Microsecond)
+		updates := map[string]interface{}{
+			"description":         expense.Description,
+			"valuetotal":          expense.ValueTotal,
+			"valuerate":           expense.ValueRate,
+			"creditstart":         expense.CreditStart,
+			"creditend":           expense.CreditEnd,
+			"type":                expense.Type,
+			"faelligkeitstag":     expense.Faelligkeitstag,
+			"zahldatum":           expense.Zahldatum,
+			"recurrence":          expense.Recurrence,
+			"recurrence_interval": expense.RecurrenceInterval,
+			"recurrence_start":    expense.RecurrenceStart,
+			"recurrence_end":      expense.RecurrenceEnd,
+			"changed_at":          expense.ChangedAt,
+		}
+		if err := tx.Model(&models.Haushaltsausgaben{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := setExpenseLabels(tx, id, expense.UserID, labelsOf(&expense)); err != nil {
+			return err
+		}
+		return loadLabels(tx, &expense)
+	})
+	if err != nil {
+		log.Printf("[DAO.Patch] ERROR patching expense ID %d: %v", id, err)
+		return nil, err
+	}
+	log.Printf("[DAO.Patch] Successfully patched expense ID %d, new version %s", id, expense.Version())
+	return &expense, nil
+}
+
 // Delete entfernt einen Eintrag
-func (dao *HaushaltsausgabenDAO) Delete(id int) error {
+func (dao *HaushaltsausgabenDAO) Delete(p auth.Principal, id int) error {
 	log.Printf("[DAO.Delete] Attempting to delete expense ID: %d...", id)
+	existing, err := dao.authorizeWrite(p, id)
+	if err != nil {
+		return err
+	}
 	// --- Logging: Vor DB-Aufruf ---
 	log.Println("[DAO.Delete] Calling db.Where().Delete()...")
-	tx := dao.db.Where("id = ?", id).Delete(&models.Haushaltsausgaben{})
+	tx := dao.db.Where("id = ? AND userid = ?", id, existing.UserID).Delete(&models.Haushaltsausgaben{})
 	if err := tx.Error; err != nil {
 		// --- Logging: Fehler beim Löschen ---
 		log.Printf("[DAO.Delete] ERROR deleting expense ID %d: %v", id, err)
 		return err
 	}
+	deleteBlobs(dao.blobs, existing.ReceiptKey)
 
 	// --- Logging: Erfolg (Anzahl betroffener Zeilen) ---
 	log.Printf("[DAO.Delete] Successfully deleted expense ID %d. Rows affected: %d", id, tx.RowsAffected)
@@ -216,17 +404,294 @@ func (dao *HaushaltsausgabenDAO) Delete(id int) error {
 	return nil
 }
 
-// GetByUserIDAndMonth holt Ausgaben für User und Monat via Raw SQL
-func (dao *HaushaltsausgabenDAO) GetByUserIDAndMonth(userID int, month string) ([]models.Haushaltsausgaben, error) {
-	log.Printf("[DAO.GetByUserIDAndMonth] Fetching expenses for UserID %d and Month %s...", userID, month)
+// OpenReceipt öffnet den Beleg einer Ausgabe zum Streamen und liefert die
+// Metadaten mit. Ohne Beleg ist der Reader nil; sonst muss ihn der Aufrufer schließen.
+func (dao *HaushaltsausgabenDAO) OpenReceipt(p auth.Principal, id int) (*models.Haushaltsausgaben, io.ReadSeekCloser, error) {
+	log.Printf("[DAO.OpenReceipt] Opening receipt of expense ID: %d...", id)
+	var expense models.Haushaltsausgaben
+	err := dao.scoped(p).
+		Select("id", "userid", "changed_at", "receipt", "receipt_key", "receipt_hash", "receipt_content_type", "receipt_filename", "receipt_size").
+		First(&expense, id).Error
+	if err != nil {
+		log.Printf("[DAO.OpenReceipt] ERROR fetching receipt of expense ID %d: %v", id, err)
+		return nil, nil, err
+	}
+
+	// Alte Belege stehen noch in der Spalte receipt
+	if expense.ReceiptKey == "" {
+		if len(expense.Receipt) == 0 {
+			return &expense, nil, nil
+		}
+		return &expense, readSeekNopCloser{bytes.NewReader(expense.Receipt)}, nil
+	}
+	content, err := dao.blobs.Open(context.Background(), expense.ReceiptKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		log.Printf("[DAO.OpenReceipt] WARN: receipt %s of expense ID %d is missing in the blob store", expense.ReceiptKey, expense.ID)
+		return &expense, nil, nil
+	}
+	if err != nil {
+		log.Printf("[DAO.OpenReceipt] ERROR opening receipt %s of expense ID %d: %v", expense.ReceiptKey, expense.ID, err)
+		return nil, nil, err
+	}
+	return &expense, content, nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
+
+// loadReceipt holt die Bytes eines Belegs aus dem BlobStore. Alte Belege stehen
+// noch in der Spalte receipt, bis `backend_go receipts migrate` sie verschiebt.
+func (dao *HaushaltsausgabenDAO) loadReceipt(expense *models.Haushaltsausgaben) error {
+	if expense.ReceiptKey == "" {
+		return nil
+	}
+	data, err := dao.blobs.Get(context.Background(), expense.ReceiptKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		log.Printf("[DAO.loadReceipt] WARN: receipt %s of expense ID %d is missing in the blob store", expense.ReceiptKey, expense.ID)
+		return nil
+	}
+	if err != nil {
+		log.Printf("[DAO.loadReceipt] ERROR reading receipt %s of expense ID %d: %v", expense.ReceiptKey, expense.ID, err)
+		return err
+	}
+	expense.Receipt = data
+	return nil
+}
+
+// GetReceiptThumbnail lädt nur das Vorschaubild (JPEG) eines Belegs
+func (dao *HaushaltsausgabenDAO) GetReceiptThumbnail(p auth.Principal, id int) ([]byte, error) {
+	var expense models.Haushaltsausgaben
+	err := dao.scoped(p).Select("id", "receipt_thumbnail").First(&expense, id).Error
+	if err != nil {
+		log.Printf("[DAO.GetReceiptThumbnail] ERROR fetching thumbnail of expense ID %d: %v", id, err)
+		return nil, err
+	}
+	return expense.ReceiptThumbnail, nil
+}
+
+// SetReceipt speichert (oder ersetzt) den Beleg einer Ausgabe. thumbnail darf nil sein.
+// Der Beleg landet zuerst im BlobStore; erst danach zeigt die Ausgabe auf ihn.
+func (dao *HaushaltsausgabenDAO) SetReceipt(p auth.Principal, id int, data []byte, contentType, filename string, thumbnail []byte) (*models.Haushaltsausgaben, error) {
+	log.Printf("[DAO.SetReceipt] Storing receipt for expense ID %d (%s, %d bytes)", id, contentType, len(data))
+	existing, err := dao.authorizeWrite(p, id)
+	if err != nil {
+		return nil, err
+	}
+	hash := receiptHash(data)
+	key := receiptKey(existing.UserID, id, hash)
+	if err := dao.blobs.Put(context.Background(), key, data); err != nil {
+		log.Printf("[DAO.SetReceipt] ERROR storing receipt of expense ID %d: %v", id, err)
+		return nil, err
+	}
+
+	expense, err := dao.updateReceipt(p, existing, map[string]interface{}{
+		"receipt":              nil,
+		"receipt_key":          key,
+		"receipt_hash":         hash,
+		"receipt_content_type": contentType,
+		"receipt_filename":     filename,
+		"receipt_size":         len(data),
+		"receipt_thumbnail":    thumbnail,
+	})
+	if err != nil {
+		if key != existing.ReceiptKey {
+			deleteBlobs(dao.blobs, key)
+		}
+		return nil, err
+	}
+	if existing.ReceiptKey != key {
+		deleteBlobs(dao.blobs, existing.ReceiptKey)
+	}
+	return expense, nil
+}
+
+// DeleteReceipt entfernt den Beleg einer Ausgabe
+func (dao *HaushaltsausgabenDAO) DeleteReceipt(p auth.Principal, id int) (*models.Haushaltsausgaben, error) {
+	log.Printf("[DAO.DeleteReceipt] Removing receipt of expense ID %d", id)
+	existing, err := dao.authorizeWrite(p, id)
+	if err != nil {
+		return nil, err
+	}
+	expense, err := dao.updateReceipt(p, existing, map[string]interface{}{
+		"receipt":              nil,
+		"receipt_key":          nil,
+		"receipt_hash":         nil,
+		"receipt_content_type": "",
+		"receipt_filename":     "",
+		"receipt_size":         0,
+		"receipt_thumbnail":    nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleteBlobs(dao.blobs, existing.ReceiptKey)
+	return expense, nil
+}
+
+// updateReceipt schreibt die Belegspalten; ein neuer Beleg ist eine Änderung der Ausgabe
+// und ergibt damit auch eine neue Version
+func (dao *HaushaltsausgabenDAO) updateReceipt(p auth.Principal, existing *models.Haushaltsausgaben, updates map[string]interface{}) (*models.Haushaltsausgaben, error) {
+	updates["changed_at"] = time.Now().Truncate(time.Microsecond)
+	if err := dao.db.Model(&models.Haushaltsausgaben{}).Where("id = ? AND userid = ?", existing.ID, existing.UserID).Updates(updates).Error; err != nil {
+		log.Printf("[DAO.updateReceipt] ERROR updating receipt of expense ID %d: %v", existing.ID, err)
+		return nil, err
+	}
+	return dao.GetByID(p, existing.ID)
+}
+
+// MigrateLegacyReceipts verschiebt Belege aus der Spalte receipt in den BlobStore,
+// jeweils batchSize Einträge pro Abfrage. Die Version der Ausgaben bleibt gleich.
+// Liefert die Anzahl verschobener Belege.
+func (dao *HaushaltsausgabenDAO) MigrateLegacyReceipts(ctx context.Context, batchSize int) (int, error) {
+	moved, lastID := 0, 0
+	for {
+		var batch []models.Haushaltsausgaben
+		err := dao.db.WithContext(ctx).
+			Select("id", "userid", "receipt").
+			Where("id > ? AND receipt IS NOT NULL AND (receipt_key IS NULL OR receipt_key = '')", lastID).
+			Order("id").Limit(batchSize).Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return moved, err
+		}
+		for _, expense := range batch {
+			lastID = expense.ID
+			hash := receiptHash(expense.Receipt)
+			key := receiptKey(expense.UserID, expense.ID, hash)
+			if err := dao.blobs.Put(ctx, key, expense.Receipt); err != nil {
+				return moved, fmt.Errorf("storing receipt of expense %d: %w", expense.ID, err)
+			}
+			// Nur umstellen, wenn der Beleg nicht inzwischen ersetzt wurde
+			res := dao.db.WithContext(ctx).Model(&models.Haushaltsausgaben{}).
+				Where("id = ? AND (receipt_key IS NULL OR receipt_key = '') AND receipt IS NOT NULL", expense.ID).
+				Updates(map[string]interface{}{
+					"receipt":      nil,
+					"receipt_key":  key,
+					"receipt_hash": hash,
+					"receipt_size": len(expense.Receipt),
+				})
+			if res.Error != nil {
+				return moved, fmt.Errorf("updating expense %d: %w", expense.ID, res.Error)
+			}
+			if res.RowsAffected == 0 {
+				deleteBlobs(dao.blobs, key)
+				continue
+			}
+			moved++
+		}
+	}
+}
+
+// receiptKey legt Belege nach Benutzer und Ausgabe ab; der Hash macht jede
+// Fassung eindeutig, damit ein Ersatz den alten Beleg nicht überschreibt
+func receiptKey(userID, expenseID int, hash string) string {
+	return fmt.Sprintf("receipts/%d/%d/%s", userID, expenseID, hash)
+}
+
+func receiptHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// deleteBlobs entfernt Objekte, nachdem die Datenbank nicht mehr auf sie zeigt.
+// Fehler werden nur protokolliert; übrig bleibt höchstens ein verwaistes Objekt.
+func deleteBlobs(blobs blobstore.BlobStore, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := blobs.Delete(context.Background(), key); err != nil {
+			log.Printf("[DAO.deleteBlobs] WARN: failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// ExpenseOccurrence ist eine konkrete Fälligkeit einer (ggf. wiederkehrenden) Ausgabe
+type ExpenseOccurrence struct {
+	Expense models.Haushaltsausgaben
+	DueDate time.Time
+}
+
+// GetOccurrences liefert alle Fälligkeiten der Ausgaben eines Users im Zeitraum [from, to),
+// sortiert nach Fälligkeit. Die Regeln werden in Go expandiert, SQL grenzt nur grob vor.
+func (dao *HaushaltsausgabenDAO) GetOccurrences(p auth.Principal, userID int, from, to time.Time) ([]ExpenseOccurrence, error) {
+	log.Printf("[DAO.GetOccurrences] Fetching occurrences for UserID %d from %s to %s...", userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if !auth.CanRead(dao.policy, p, userID) {
+		log.Printf("[DAO.GetOccurrences] DENIED: UserID %d may not read expenses of UserID %d", p.UserID, userID)
+		return nil, auth.ErrForbidden
+	}
+
+	// Start und Ende gelten monatsweise, daher wird der Zeitraum auf ganze Monate erweitert
+	firstMonth, _ := recurrence.MonthRange(from)
+	_, afterLastMonth := recurrence.MonthRange(to)
 	var expenses []models.Haushaltsausgaben
-	// --- Logging: Vor DB-Aufruf ---
-	// Bereinige das Query-Logging, um nicht das ganze Query zu loggen, falls es sensibel ist
-	log.Printf("[DAO.GetByUserIDAndMonth] Executing Raw Query with params: UserID=%d, Month=%s", userID, month)
-	if err := dao.db.Raw(ExpensesQuery, userID, month, month, month).Scan(&expenses).Error; err != nil {
-		log.Printf("[DAO.GetByUserIDAndMonth] ERROR fetching expenses for UserID %d, Month %s: %v", userID, month, err)
+	err := dao.db.Scopes(withoutReceipt, withLabels).
+		Where("userid = ? AND recurrence_start < ? AND (recurrence_end IS NULL OR recurrence_end >= ?)", userID, afterLastMonth, firstMonth).
+		Find(&expenses).Error
+	if err != nil {
+		log.Printf("[DAO.GetOccurrences] ERROR fetching expenses for UserID %d: %v", userID, err)
 		return nil, err
 	}
-	log.Printf("[DAO.GetByUserIDAndMonth] Fetched %d expenses for UserID %d, Month %s.", len(expenses), userID, month)
-	return expenses, nil
+
+	occurrences := []ExpenseOccurrence{}
+	for _, expense := range expenses {
+		for _, due := range expense.RecurrenceRule().Occurrences(from, to) {
+			occurrences = append(occurrences, ExpenseOccurrence{Expense: expense, DueDate: due})
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].DueDate.Before(occurrences[j].DueDate)
+	})
+	log.Printf("[DAO.GetOccurrences] Expanded %d expenses into %d occurrences for UserID %d.", len(expenses), len(occurrences), userID)
+	return occurrences, nil
+}
+
+// loadLabels lädt Kategorien und Tags einer bereits gelesenen Ausgabe nach
+func loadLabels(tx *gorm.DB, expense *models.Haushaltsausgaben) error {
+	var labeled models.Haushaltsausgaben
+	if err := tx.Scopes(withLabels).Select("id").First(&labeled, expense.ID).Error; err != nil {
+		return err
+	}
+	expense.Categories, expense.Tags = labeled.Categories, labeled.Tags
+	return nil
+}
+
+// labelsOf liefert die Zuordnungen des Modells; leere statt nil-Slices, damit
+// setExpenseLabels sie auch dann übernimmt
+func labelsOf(expense *models.Haushaltsausgaben) ExpenseLabels {
+	labels := ExpenseLabels{CategoryIDs: []int{}, Tags: []string{}}
+	for _, c := range expense.Categories {
+		labels.CategoryIDs = append(labels.CategoryIDs, c.ID)
+	}
+	for _, t := range expense.Tags {
+		labels.Tags = append(labels.Tags, t.Name)
+	}
+	return labels
+}
+
+// validateRecurrence prüft eine Regel und kennzeichnet Fehler als ErrInvalidRecurrence
+func validateRecurrence(rule recurrence.Rule) error {
+	if err := rule.Validate(); err != nil {
+		log.Printf("[DAO.validateRecurrence] Invalid recurrence rule %+v: %v", rule, err)
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return nil
+}
+
+// authorizeWrite lädt den Eintrag im Sichtbereich des Principals und prüft das Schreibrecht.
+// Nicht sichtbare Einträge ergeben gorm.ErrRecordNotFound, sichtbare ohne Schreibrecht auth.ErrForbidden.
+func (dao *HaushaltsausgabenDAO) authorizeWrite(p auth.Principal, id int) (*models.Haushaltsausgaben, error) {
+	existing, err := dao.GetByID(p, id)
+	if err != nil {
+		return nil, err
+	}
+	if !dao.policy.CanWrite(p, existing.UserID) {
+		log.Printf("[DAO.authorizeWrite] DENIED: UserID %d may not modify expense ID %d of UserID %d", p.UserID, id, existing.UserID)
+		return nil, auth.ErrForbidden
+	}
+	return existing, nil
 }
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// testUser creates a verified user that is deleted after the test.
+func testUser(t *testing.T, database *gorm.DB, prefix string) *models.User {
+	t.Helper()
+	users := NewUserDAO(database, nil)
+	user, err := users.Create(prefix+" Test", "", fmt.Sprintf("%s-%d@example.com", prefix, time.Now().UnixNano()), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := users.Delete(user.ID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+	return user
+}
+
+func TestUpdateWithoutRuleFollowsNewType(t *testing.T) {
+	database := testDB(t)
+	user := testUser(t, database, "expense")
+	expenses := NewHaushaltsausgabenDAO(database, auth.OwnerOnlyPolicy{}, nil)
+	p := auth.Principal{UserID: user.ID, Role: auth.RoleMember}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	credit, err := expenses.Create(p, "Auto", 12000, 1000, start, end, "credit", "1", time.Time{}, nil, ExpenseLabels{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shortening the credit without sending a rule moves the end of the rule
+	shorter := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	if err := expenses.Update(p, credit.ID, "Auto", 12000, 1000, start, shorter, "credit", "1", nil, ExpenseLabels{}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := expenses.GetByID(p, credit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.RecurrenceEnd == nil || !updated.RecurrenceEnd.Equal(shorter) {
+		t.Fatalf("recurrence end = %v, want %v", updated.RecurrenceEnd, shorter)
+	}
+
+	// A rule that was set explicitly is kept
+	quarterly := recurrence.Rule{Frequency: recurrence.EveryNMonths, Interval: 3, Start: start}
+	if err := expenses.Update(p, credit.ID, "Auto", 12000, 1000, start, shorter, "credit", "1", &quarterly, ExpenseLabels{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := expenses.Update(p, credit.ID, "Auto", 12000, 1000, start, end, "monthlycosts", "1", nil, ExpenseLabels{}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err = expenses.GetByID(p, credit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Recurrence != string(recurrence.EveryNMonths) || updated.RecurrenceInterval != 3 {
+		t.Fatalf("explicit rule replaced: %s/%d", updated.Recurrence, updated.RecurrenceInterval)
+	}
+
+	// Turning monthly costs into an invoice makes them a one-off expense
+	costs, err := expenses.Create(p, "Strom", 80, 0, time.Time{}, time.Time{}, "monthlycosts", "", time.Time{}, nil, ExpenseLabels{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expenses.Update(p, costs.ID, "Strom", 80, 0, time.Time{}, time.Time{}, "invoice", "", nil, ExpenseLabels{}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err = expenses.GetByID(p, costs.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Recurring() {
+		t.Fatalf("invoice still recurring: %s", updated.Recurrence)
+	}
+}
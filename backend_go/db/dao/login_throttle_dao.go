@@ -0,0 +1,144 @@
+package dao
+
+import (
+	"backend_go/db/models"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ThrottlePolicy describes when a key gets locked and for how long.
+// After Threshold failures every further failure doubles the lock, starting
+// at BaseDelay and capped at MaxDelay. Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+var (
+	// AccountThrottlePolicy protects a single account against password guessing.
+	AccountThrottlePolicy = ThrottlePolicy{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: 24 * time.Hour}
+	// IPThrottlePolicy limits how many accounts one client can try.
+	IPThrottlePolicy = ThrottlePolicy{Threshold: 20, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	// PasswordResetThrottlePolicy limits reset mails to one address. Every
+	// request counts, not only failed ones.
+	PasswordResetThrottlePolicy = ThrottlePolicy{Threshold: 3, BaseDelay: 15 * time.Minute, MaxDelay: 24 * time.Hour, Window: time.Hour}
+	// PasswordResetIPThrottlePolicy limits how many addresses one client can send reset mails to.
+	PasswordResetIPThrottlePolicy = ThrottlePolicy{Threshold: 10, BaseDelay: 15 * time.Minute, MaxDelay: 24 * time.Hour, Window: time.Hour}
+)
+
+// lockDuration returns how long a key is locked after the given number of failures.
+func (p ThrottlePolicy) lockDuration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	exp := failures - p.Threshold
+	if exp > 30 {
+		return p.MaxDelay
+	}
+	d := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(exp)))
+	if d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// IPThrottleKey returns the throttle key of a client address.
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// AccountThrottleKey returns the throttle key of an account, given by its email
+// (or the identifier tried, if no account matches).
+func AccountThrottleKey(identifier string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// UserThrottleKey returns the throttle key used for second factor attempts of a user.
+func UserThrottleKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// PasswordResetThrottleKey returns the throttle key of reset requests for an
+// email address. It is separate from the account key, so requesting reset mails
+// cannot lock anyone out of their login.
+func PasswordResetThrottleKey(email string) string {
+	return "reset:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// PasswordResetIPThrottleKey returns the throttle key of reset requests from a client address.
+func PasswordResetIPThrottleKey(ip string) string {
+	return "reset-ip:" + ip
+}
+
+// LoginThrottleDAO keeps failed login counters and lockouts.
+type LoginThrottleDAO struct {
+	db *gorm.DB
+}
+
+// NewLoginThrottleDAO initializes and returns a new LoginThrottleDAO.
+func NewLoginThrottleDAO(db *gorm.DB) *LoginThrottleDAO {
+	return &LoginThrottleDAO{db: db}
+}
+
+// RetryAfter returns how long the longest lock among the keys still lasts, or 0.
+func (dao *LoginThrottleDAO) RetryAfter(keys ...string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	if err := dao.db.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, t := range throttles {
+		if remaining := time.Until(*t.LockedUntil); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed attempt for key and locks it according to the policy.
+func (dao *LoginThrottleDAO) RecordFailure(key string, policy ThrottlePolicy) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var throttle models.LoginThrottle
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&throttle).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			throttle = models.LoginThrottle{Key: key}
+		} else if err != nil {
+			return err
+		}
+
+		if now.Sub(throttle.LastFailureAt) > policy.Window {
+			throttle.Failures = 0
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		if d := policy.lockDuration(throttle.Failures); d > 0 {
+			lockedUntil := now.Add(d)
+			throttle.LockedUntil = &lockedUntil
+		}
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&throttle).Error
+	})
+}
+
+// Clear removes the counter and lock of a key, e.g. after a successful login
+// or when an admin unlocks an account.
+func (dao *LoginThrottleDAO) Clear(key string) error {
+	return dao.db.Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
+
+// ListLocked returns all keys that are locked right now.
+func (dao *LoginThrottleDAO) ListLocked() ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	err := dao.db.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&throttles).Error
+	return throttles, err
+}
@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyLockDuration(t *testing.T) {
+	policy := ThrottlePolicy{Threshold: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Window: time.Hour}
+	for failures, want := range map[int]time.Duration{
+		0:   0,
+		2:   0,
+		3:   time.Second,
+		4:   2 * time.Second,
+		6:   8 * time.Second,
+		7:   10 * time.Second,
+		100: 10 * time.Second,
+	} {
+		if got := policy.lockDuration(failures); got != want {
+			t.Errorf("lockDuration(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestLoginThrottleLocksAfterThreshold(t *testing.T) {
+	throttle := NewLoginThrottleDAO(testDB(t))
+	key := AccountThrottleKey(fmt.Sprintf("throttle-%d@example.com", time.Now().UnixNano()))
+	other := AccountThrottleKey(fmt.Sprintf("other-%d@example.com", time.Now().UnixNano()))
+	t.Cleanup(func() { throttle.Clear(key) })
+	policy := ThrottlePolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+	if err := throttle.RecordFailure(key, policy); err != nil {
+		t.Fatal(err)
+	}
+	if wait, err := throttle.RetryAfter(key); err != nil || wait != 0 {
+		t.Fatalf("locked below the threshold: %v, %v", wait, err)
+	}
+
+	if err := throttle.RecordFailure(key, policy); err != nil {
+		t.Fatal(err)
+	}
+	wait, err := throttle.RetryAfter(other, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Minute {
+		t.Fatalf("first lock lasts %v, want up to a minute", wait)
+	}
+
+	// Every further failure doubles the lock
+	if err := throttle.RecordFailure(key, policy); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ = throttle.RetryAfter(key); wait <= time.Minute || wait > 2*time.Minute {
+		t.Fatalf("second lock lasts %v, want up to two minutes", wait)
+	}
+	if wait, _ = throttle.RetryAfter(other); wait != 0 {
+		t.Fatalf("unrelated key is locked for %v", wait)
+	}
+
+	if err := throttle.Clear(key); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ = throttle.RetryAfter(key); wait != 0 {
+		t.Fatalf("still locked after Clear: %v", wait)
+	}
+}
+
+func TestLoginThrottleForgetsOldFailures(t *testing.T) {
+	throttle := NewLoginThrottleDAO(testDB(t))
+	key := AccountThrottleKey(fmt.Sprintf("window-%d@example.com", time.Now().UnixNano()))
+	t.Cleanup(func() { throttle.Clear(key) })
+	// With a zero window every failure starts counting from one again
+	policy := ThrottlePolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: 0}
+
+	for i := 0; i < 3; i++ {
+		if err := throttle.RecordFailure(key, policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wait, err := throttle.RetryAfter(key); err != nil || wait != 0 {
+		t.Fatalf("failures outside the window locked the key: %v, %v", wait, err)
+	}
+}
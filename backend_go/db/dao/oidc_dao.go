@@ -0,0 +1,157 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrOIDCLoginInvalid is returned for unknown, expired or already used login
+// states and login codes.
+var ErrOIDCLoginInvalid = errors.New("oidc login invalid")
+
+// oidcLoginCodeTTL is how long the frontend has to exchange a login code.
+const oidcLoginCodeTTL = 2 * time.Minute
+
+// OIDCDAO stores pending OpenID Connect logins and the external identities
+// linked to users.
+type OIDCDAO struct {
+	db *gorm.DB
+}
+
+// NewOIDCDAO initializes and returns a new OIDCDAO.
+func NewOIDCDAO(db *gorm.DB) *OIDCDAO {
+	return &OIDCDAO{db: db}
+}
+
+// StartLogin stores a new login attempt and returns the plaintext state that is
+// sent to the provider. Expired attempts are cleaned up on the way.
+func (dao *OIDCDAO) StartLogin(provider, nonce, codeVerifier string, ttl time.Duration) (string, error) {
+	if err := dao.db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLogin{}).Error; err != nil {
+		return "", err
+	}
+
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	login := &models.OIDCLogin{
+		Provider:     provider,
+		StateHash:    hash,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if err := dao.db.Create(login).Error; err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// PendingLogin returns the open login attempt for the state returned by the
+// provider. The state stays valid until CompleteLogin.
+func (dao *OIDCDAO) PendingLogin(provider, state string) (*models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	err := dao.db.Where("state_hash = ? AND provider = ? AND completed_at IS NULL AND expires_at > ?",
+		auth.HashToken(state), provider, time.Now()).
+		First(&login).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOIDCLoginInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// CompleteLogin marks the attempt as done for the user and returns a single-use
+// login code for the frontend.
+func (dao *OIDCDAO) CompleteLogin(id, userID int) (string, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	res := dao.db.Model(&models.OIDCLogin{}).
+		Where("id = ? AND completed_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"user_id":         userID,
+			"login_code_hash": hash,
+			"completed_at":    now,
+			"expires_at":      now.Add(oidcLoginCodeTTL),
+		})
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		return "", ErrOIDCLoginInvalid
+	}
+	return plain, nil
+}
+
+// FailLogin discards an attempt that did not lead to a login.
+func (dao *OIDCDAO) FailLogin(id int) error {
+	return dao.db.Delete(&models.OIDCLogin{}, id).Error
+}
+
+// ConsumeLoginCode redeems a login code and returns the user it was issued for.
+func (dao *OIDCDAO) ConsumeLoginCode(code string) (int, error) {
+	var userID int
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		var login models.OIDCLogin
+		err := tx.Where("login_code_hash = ? AND expires_at > ?", auth.HashToken(code), time.Now()).
+			First(&login).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && login.UserID == nil) {
+			return ErrOIDCLoginInvalid
+		}
+		if err != nil {
+			return err
+		}
+		// Deleting the row makes the code single use, concurrent redemptions lose here
+		res := tx.Delete(&models.OIDCLogin{}, login.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOIDCLoginInvalid
+		}
+		userID = *login.UserID
+		return nil
+	})
+	return userID, err
+}
+
+// GetIdentity returns the linked identity for issuer and subject, or nil if the
+// external account is not linked yet.
+func (dao *OIDCDAO) GetIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := dao.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// LinkIdentity links the external account to the user.
+func (dao *OIDCDAO) LinkIdentity(userID int, issuer, subject, email string) error {
+	now := time.Now()
+	return dao.db.Create(&models.UserIdentity{
+		UserID:      userID,
+		Issuer:      issuer,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: &now,
+	}).Error
+}
+
+// TouchIdentity records a login through the identity.
+func (dao *OIDCDAO) TouchIdentity(id int, email string) error {
+	return dao.db.Model(&models.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_login_at": time.Now(), "email": email}).Error
+}
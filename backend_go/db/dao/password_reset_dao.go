@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrResetTokenInvalid is returned for unknown, expired or already used reset tokens.
+var ErrResetTokenInvalid = errors.New("password reset token invalid")
+
+// PasswordResetDAO stores hashed, single-use password reset tokens.
+type PasswordResetDAO struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetDAO initializes and returns a new PasswordResetDAO.
+func NewPasswordResetDAO(db *gorm.DB) *PasswordResetDAO {
+	return &PasswordResetDAO{db: db}
+}
+
+// Create stores a new reset token for the user and returns the plaintext token.
+func (dao *PasswordResetDAO) Create(userID int, ttl time.Duration) (string, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	token := &models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := dao.db.Create(token).Error; err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// Consume marks the token as used and returns the user it was issued for.
+// All other open reset tokens of that user are invalidated as well.
+func (dao *PasswordResetDAO) Consume(plain string) (int, error) {
+	var userID int
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(plain), time.Now()).
+			First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrResetTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// A concurrent request consumed the token first
+			return ErrResetTokenInvalid
+		}
+		userID = token.UserID
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
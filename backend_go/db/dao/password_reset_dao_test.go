@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	database := testDB(t)
+	user := testUser(t, database, "reset")
+	resets := NewPasswordResetDAO(database)
+
+	first, err := resets.Create(user.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := resets.Create(user.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := resets.Consume(first)
+	if err != nil || userID != user.ID {
+		t.Fatalf("Consume = %d, %v", userID, err)
+	}
+	if _, err := resets.Consume(first); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("token used twice: %v", err)
+	}
+	// Using one token invalidates the other open ones
+	if _, err := resets.Consume(second); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("older token still valid: %v", err)
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	database := testDB(t)
+	user := testUser(t, database, "reset")
+	resets := NewPasswordResetDAO(database)
+
+	expired, err := resets.Create(user.ID, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resets.Consume(expired); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("expired token accepted: %v", err)
+	}
+	if _, err := resets.Consume("unknown-token"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("unknown token accepted: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned for unknown, expired or revoked refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+	// ErrRefreshTokenReused is returned when an already rotated token is presented again.
+	// The whole token family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Session describes one active login, i.e. one refresh token family.
+type Session struct {
+	ID           string    `json:"id"`
+	UserAgent    string    `json:"user_agent"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RefreshTokenDAO stores hashed refresh tokens and handles their rotation.
+type RefreshTokenDAO struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenDAO initializes and returns a new RefreshTokenDAO.
+func NewRefreshTokenDAO(db *gorm.DB) *RefreshTokenDAO {
+	return &RefreshTokenDAO{db: db}
+}
+
+// Create starts a new token family for the user and returns the plaintext token.
+func (dao *RefreshTokenDAO) Create(userID int, userAgent string, ttl time.Duration) (string, *models.RefreshToken, error) {
+	familyID, err := auth.NewRandomID()
+	if err != nil {
+		return "", nil, err
+	}
+	return dao.insert(dao.db, userID, familyID, userAgent, ttl)
+}
+
+func (dao *RefreshTokenDAO) insert(tx *gorm.DB, userID int, familyID, userAgent string, ttl time.Duration) (string, *models.RefreshToken, error) {
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// Rotate exchanges a refresh token for a new one in the same family. Presenting a
+// token that was already rotated revokes the whole family and returns ErrRefreshTokenReused.
+func (dao *RefreshTokenDAO) Rotate(plain, userAgent string, ttl time.Duration) (string, *models.RefreshToken, error) {
+	var current models.RefreshToken
+	if err := dao.db.Where("token_hash = ?", auth.HashToken(plain)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, ErrRefreshTokenInvalid
+		}
+		return "", nil, err
+	}
+
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return "", nil, ErrRefreshTokenInvalid
+	}
+	if current.UsedAt != nil {
+		log.Printf("[RefreshTokenDAO.Rotate] WARN: reuse of rotated refresh token detected, revoking family %s of user %d", current.FamilyID, current.UserID)
+		if err := dao.RevokeFamily(current.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReused
+	}
+
+	var newPlain string
+	var next *models.RefreshToken
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		// Only one concurrent request may consume the token
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		newPlain, next, err = dao.insert(tx, current.UserID, current.FamilyID, userAgent, ttl)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := dao.RevokeFamily(current.FamilyID); revokeErr != nil {
+			return "", nil, revokeErr
+		}
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return newPlain, next, nil
+}
+
+// GetByToken returns the token row for a plaintext refresh token.
+func (dao *RefreshTokenDAO) GetByToken(plain string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := dao.db.Where("token_hash = ?", auth.HashToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeFamily revokes every token of a session.
+func (dao *RefreshTokenDAO) RevokeFamily(familyID string) error {
+	return dao.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserFamily revokes a session only if it belongs to the given user.
+// It returns gorm.ErrRecordNotFound if the user has no such session.
+func (dao *RefreshTokenDAO) RevokeUserFamily(userID int, familyID string) error {
+	res := dao.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser logs the user out on all devices.
+func (dao *RefreshTokenDAO) RevokeAllForUser(userID int) error {
+	return dao.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeOtherSessions logs the user out on all devices except the session keepFamilyID.
+func (dao *RefreshTokenDAO) RevokeOtherSessions(userID int, keepFamilyID string) error {
+	return dao.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// IsSessionActive reports whether the family still has a usable token.
+func (dao *RefreshTokenDAO) IsSessionActive(familyID string) (bool, error) {
+	var count int64
+	err := dao.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListSessions returns the active sessions of a user.
+func (dao *RefreshTokenDAO) ListSessions(userID int) ([]Session, error) {
+	var tokens []models.RefreshToken
+	err := dao.db.Where("user_id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, Session{
+			ID:           t.FamilyID,
+			UserAgent:    t.UserAgent,
+			LastActiveAt: t.CreatedAt,
+			ExpiresAt:    t.ExpiresAt,
+		})
+	}
+	return sessions, nil
+}
@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevokeOtherSessionsKeepsTheCurrentOne(t *testing.T) {
+	database := testDB(t)
+	user := testUser(t, database, "sessions")
+	sessions := NewRefreshTokenDAO(database)
+
+	_, current, err := sessions.Create(user.ID, "current", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := sessions.Create(user.ID, "other", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sessions.RevokeOtherSessions(user.ID, current.FamilyID); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := sessions.IsSessionActive(current.FamilyID); err != nil || !active {
+		t.Fatalf("current session revoked: %v", err)
+	}
+	if active, err := sessions.IsSessionActive(other.FamilyID); err != nil || active {
+		t.Fatalf("other session still active: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeCount is the number of recovery codes handed out when 2FA is enabled.
+const RecoveryCodeCount = 10
+
+var (
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user that already uses 2FA.
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTwoFactorNotEnrolled is returned when there is no (pending) TOTP secret.
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication not enrolled")
+	// ErrTwoFactorCodeInvalid is returned for wrong, expired or replayed codes.
+	ErrTwoFactorCodeInvalid = errors.New("two-factor code invalid")
+)
+
+// TwoFactorDAO manages encrypted TOTP secrets and recovery codes.
+type TwoFactorDAO struct {
+	db  *gorm.DB
+	box *auth.SecretBox
+}
+
+// NewTwoFactorDAO initializes and returns a new TwoFactorDAO. Secrets are sealed with box.
+func NewTwoFactorDAO(db *gorm.DB, box *auth.SecretBox) *TwoFactorDAO {
+	return &TwoFactorDAO{db: db, box: box}
+}
+
+func secretAD(userID int) []byte {
+	return []byte(fmt.Sprintf("totp:user:%d", userID))
+}
+
+// StartEnrollment creates a new, not yet enabled TOTP secret for the user and
+// returns it in base32 form. A pending enrollment is replaced.
+func (dao *TwoFactorDAO) StartEnrollment(userID int) (string, error) {
+	existing, err := dao.get(userID)
+	if err != nil && !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		return "", err
+	}
+	if existing != nil && existing.EnabledAt != nil {
+		return "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := dao.box.Seal([]byte(secret), secretAD(userID))
+	if err != nil {
+		return "", err
+	}
+
+	row := &models.UserTOTP{UserID: userID, SecretEncrypted: sealed}
+	if err := dao.db.Save(row).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Enable activates a pending enrollment after checking a first code and
+// returns freshly generated recovery codes in plaintext.
+func (dao *TwoFactorDAO) Enable(userID int, code string) ([]string, error) {
+	row, err := dao.get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if row.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := dao.box.Open(row.SecretEncrypted, secretAD(userID))
+	if err != nil {
+		return nil, err
+	}
+	step, ok := auth.ValidateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	var codes []string
+	err = dao.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.UserTOTP{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"enabled_at": now, "last_used_step": step}).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// IsEnabled reports whether the user has to pass a second factor on login.
+func (dao *TwoFactorDAO) IsEnabled(userID int) (bool, error) {
+	row, err := dao.get(userID)
+	if errors.Is(err, ErrTwoFactorNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return row.EnabledAt != nil, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+// Every TOTP code and recovery code can only be used once.
+func (dao *TwoFactorDAO) Verify(userID int, code string) error {
+	row, err := dao.get(userID)
+	if err != nil {
+		return err
+	}
+	if row.EnabledAt == nil {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	secret, err := dao.box.Open(row.SecretEncrypted, secretAD(userID))
+	if err != nil {
+		return err
+	}
+	if step, ok := auth.ValidateTOTPOnce(string(secret), code, time.Now(), row.LastUsedStep); ok {
+		// The condition catches a concurrent login that used the same code
+		res := dao.db.Model(&models.UserTOTP{}).
+			Where("user_id = ? AND last_used_step < ?", userID, step).
+			Update("last_used_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	res := dao.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, auth.HashToken(auth.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and returns new ones.
+func (dao *TwoFactorDAO) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	var codes []string
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// RemainingRecoveryCodes returns how many unused recovery codes the user has left.
+func (dao *TwoFactorDAO) RemainingRecoveryCodes(userID int) (int64, error) {
+	var count int64
+	err := dao.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// Disable removes the TOTP secret and all recovery codes of the user.
+func (dao *TwoFactorDAO) Disable(userID int) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error
+	})
+}
+
+func (dao *TwoFactorDAO) get(userID int) (*models.UserTOTP, error) {
+	var row models.UserTOTP
+	if err := dao.db.Where("user_id = ?", userID).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	return &row, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes, err := auth.NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code))})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"backend_go/auth"
+	"backend_go/db"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it; without it the test is
+// skipped. Use a throwaway database, the tests create and delete users.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// enrolledUser creates a user with enabled two-factor authentication and
+// returns its ID, the TOTP secret and the recovery codes.
+func enrolledUser(t *testing.T, database *gorm.DB, twoFactor *TwoFactorDAO) (int, string, []string) {
+	t.Helper()
+	users := NewUserDAO(database, nil)
+	user, err := users.Create("2FA Test", "", fmt.Sprintf("2fa-%d@example.com", time.Now().UnixNano()), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := users.Delete(user.ID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+
+	secret, err := twoFactor.StartEnrollment(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enable with the previous step, so the current code is still unused
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now())-1)
+	codes, err := twoFactor.Enable(user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, secret, codes
+}
+
+func testTwoFactorDAO(t *testing.T, database *gorm.DB) *TwoFactorDAO {
+	t.Helper()
+	box, err := auth.NewSecretBox([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTwoFactorDAO(database, box)
+}
+
+func TestTwoFactorVerifyRejectsReplayedCode(t *testing.T) {
+	database := testDB(t)
+	twoFactor := testTwoFactorDAO(t, database)
+	userID, secret, _ := enrolledUser(t, database, twoFactor)
+
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if err := twoFactor.Verify(userID, code); err != nil {
+		t.Fatalf("first use rejected: %v", err)
+	}
+	if err := twoFactor.Verify(userID, code); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("replayed code: got %v", err)
+	}
+}
+
+func TestTwoFactorRecoveryCodesWorkOnce(t *testing.T) {
+	database := testDB(t)
+	twoFactor := testTwoFactorDAO(t, database)
+	userID, _, codes := enrolledUser(t, database, twoFactor)
+
+	if err := twoFactor.Verify(userID, codes[0]); err != nil {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+	if err := twoFactor.Verify(userID, codes[0]); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("recovery code accepted twice: %v", err)
+	}
+	remaining, err := twoFactor.RemainingRecoveryCodes(userID)
+	if err != nil || remaining != int64(len(codes)-1) {
+		t.Fatalf("remaining codes: %d, %v", remaining, err)
+	}
+
+	// Regenerating invalidates the unused old codes
+	fresh, err := twoFactor.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := twoFactor.Verify(userID, codes[1]); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("old recovery code accepted after regenerating: %v", err)
+	}
+	if err := twoFactor.Verify(userID, fresh[0]); err != nil {
+		t.Fatalf("new recovery code rejected: %v", err)
+	}
+}
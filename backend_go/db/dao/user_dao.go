@@ -1,27 +1,88 @@
 package dao
 
 import (
+	"backend_go/auth"
+	"backend_go/blobstore"
 	"backend_go/db/models"
 	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// UserDAO is a struct that holds the GORM database connection.
+// dummyPasswordHash is compared against when no user matches, so a failed
+// login takes about as long for unknown accounts as for wrong passwords.
+const dummyPasswordHash = "$2a$12$eGe4tdcuCyf.TczIDRh/CuLXEGEe8p9bwR7cwifDqNEhd9SPitcpq"
+
+// ErrInvalidUsername is returned for usernames that break the rules of ValidateUsername.
+var ErrInvalidUsername = errors.New("username must be 3-32 characters of letters, digits, '.', '_' or '-' and must not be numeric only")
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+var numericPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// ValidateUsername checks the username rules. Usernames cannot contain '@' and
+// cannot be purely numeric, so they never collide with emails or user IDs.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) || numericPattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// UserDAO is a struct that holds the GORM database connection and the blob
+// store with the receipts, which are removed together with an account.
 type UserDAO struct {
-	db *gorm.DB
+	db    *gorm.DB
+	blobs blobstore.BlobStore
 }
 
 // NewUserDAO initializes and returns a new UserDAO with a GORM DB connection.
-func NewUserDAO(db *gorm.DB) *UserDAO {
-	return &UserDAO{db: db}
+func NewUserDAO(db *gorm.DB, blobs blobstore.BlobStore) *UserDAO {
+	return &UserDAO{db: db, blobs: blobs}
 }
 
 // CRUD methods
 
-// Create inserts a new user into the database.
-func (dao *UserDAO) Create(name, email, password string) (*models.User, error) {
-	user := &models.User{Name: name, Email: email, Password: password}
+// Create inserts a new user into the database. The password is stored as a bcrypt hash.
+// An empty username leaves the column NULL; such users log in with their email.
+func (dao *UserDAO) Create(name, username, email, password string) (*models.User, error) {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Name: name, Email: email, Password: hash, Role: auth.RoleMember}
+	if username != "" {
+		if err := ValidateUsername(username); err != nil {
+			return nil, err
+		}
+		user.Username = &username
+	}
+	if err := dao.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateExternal inserts a user that signed in through an external identity
+// provider. The email counts as verified, and the password is random, so the
+// account can only be used with a password after a password reset.
+func (dao *UserDAO) CreateExternal(name, email string) (*models.User, error) {
+	random, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := auth.HashPassword(random)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user := &models.User{Name: name, Email: email, Password: hash, Role: auth.RoleMember, VerifiedAt: &now}
 	if err := dao.db.Create(user).Error; err != nil {
 		return nil, err
 	}
@@ -37,27 +98,253 @@ func (dao *UserDAO) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
+// UserChanges describes an update of a user. Name and Email are always written,
+// an empty Password and nil pointers keep the stored values.
+type UserChanges struct {
+	Name           string
+	Email          string
+	Password       string
+	Username       *string
+	Income         *float32
+	Accountbalance *float32
+}
+
 // Update updates the user's details, including the password if provided.
-func (dao *UserDAO) Update(id int, name, email, password string) error {
-	user := &models.User{ID: id, Name: name, Email: email, Password: password}
-	if err := dao.db.Save(user).Error; err != nil {
+func (dao *UserDAO) Update(id int, changes UserChanges) error {
+	updates := map[string]interface{}{
+		"name":  changes.Name,
+		"email": changes.Email,
+		// A new address has to be confirmed again; only the case may change freely
+		"verified_at": gorm.Expr("CASE WHEN LOWER(email) = LOWER(?) THEN verified_at END", changes.Email),
+	}
+	if changes.Username != nil {
+		if err := ValidateUsername(*changes.Username); err != nil {
+			return err
+		}
+		updates["username"] = *changes.Username
+	}
+	if changes.Income != nil {
+		updates["income"] = *changes.Income
+	}
+	if changes.Accountbalance != nil {
+		updates["accountbalance"] = *changes.Accountbalance
+	}
+	if changes.Password != "" {
+		hash, err := auth.HashPassword(changes.Password)
+		if err != nil {
+			return err
+		}
+		updates["password"] = hash
+	}
+	if err := dao.db.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-// Delete deletes a user from the database.
+// CheckPassword verifies the password of an already identified user.
+func (dao *UserDAO) CheckPassword(id int, password string) (bool, error) {
+	user, err := dao.GetByID(id)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return auth.CheckPassword(user.Password, password), nil
+}
+
+// SetPassword replaces the stored password of a user with a fresh hash.
+func (dao *UserDAO) SetPassword(id int, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return dao.db.Model(&models.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// Delete deletes a user together with all their expenses, receipts, sessions and
+// tokens in one transaction. The last remaining admin cannot be deleted.
 func (dao *UserDAO) Delete(id int) error {
-	if err := dao.db.Delete(&models.User{}, id).Error; err != nil {
+	var receiptKeys []string
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockDeletableUser(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Haushaltsausgaben{}).Where("userid = ? AND receipt_key <> ''", id).Pluck("receipt_key", &receiptKeys).Error; err != nil {
+			return err
+		}
+		return deleteUserData(tx, id)
+	})
+	if err != nil {
 		return err
 	}
+	// Receipts are only removed once nothing references them anymore
+	deleteBlobs(dao.blobs, receiptKeys...)
 	return nil
 }
 
-// GetByEmail checks if a user with the given email exists.
+// ScheduleDeletion marks the account for deletion at the given time. Until then
+// the deletion can be cancelled by logging in again.
+func (dao *UserDAO) ScheduleDeletion(id int, at time.Time) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockDeletableUser(tx, id); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", id).Update("deletion_scheduled_at", at).Error
+	})
+}
+
+// CancelDeletion removes a pending deletion of the account.
+func (dao *UserDAO) CancelDeletion(id int) error {
+	return dao.db.Model(&models.User{}).Where("id = ?", id).Update("deletion_scheduled_at", nil).Error
+}
+
+// PurgeScheduledDeletions deletes all accounts whose grace period ended before now.
+// It returns the IDs of the deleted accounts.
+func (dao *UserDAO) PurgeScheduledDeletions(now time.Time) ([]int, error) {
+	var ids []int
+	if err := dao.db.Model(&models.User{}).Where("deletion_scheduled_at <= ?", now).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	purged := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if err := dao.Delete(id); err != nil {
+			log.Printf("Failed to purge user %d: %v", id, err)
+			continue
+		}
+		purged = append(purged, id)
+	}
+	return purged, nil
+}
+
+// lockDeletableUser locks the user row and makes sure removing the user does not
+// leave the system without an admin.
+func lockDeletableUser(tx *gorm.DB, id int) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	if user.Role == auth.RoleAdmin {
+		var admins int64
+		if err := tx.Model(&models.User{}).Where("role = ?", auth.RoleAdmin).Count(&admins).Error; err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+	return &user, nil
+}
+
+// deleteUserData removes the user row and every row that references it.
+func deleteUserData(tx *gorm.DB, id int) error {
+	related := []struct {
+		model interface{}
+		where string
+		arg   interface{}
+	}{
+		{&models.Haushaltsausgaben{}, "userid = ?", id},
+		{&models.Budget{}, "user_id = ?", id},
+		{&models.Tag{}, "user_id = ?", id},
+		{&models.Category{}, "user_id = ?", id},
+		{&models.RefreshToken{}, "user_id = ?", id},
+		{&models.APIToken{}, "user_id = ?", id},
+		{&models.PasswordResetToken{}, "user_id = ?", id},
+		{&models.EmailVerificationToken{}, "user_id = ?", id},
+		{&models.RecoveryCode{}, "user_id = ?", id},
+		{&models.UserTOTP{}, "user_id = ?", id},
+		{&models.UserIdentity{}, "user_id = ?", id},
+		{&models.OIDCLogin{}, "user_id = ?", id},
+		{&models.LoginThrottle{}, "key = ?", UserThrottleKey(id)},
+	}
+	for _, r := range related {
+		if err := tx.Where(r.where, r.arg).Delete(r.model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.User{}, id).Error
+}
+
+// GetByID returns the user with the given ID, or nil if there is none.
+func (dao *UserDAO) GetByID(id int) (*models.User, error) {
+	var user models.User
+	if err := dao.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ErrLastAdmin is returned when a change would leave the system without an admin.
+var ErrLastAdmin = errors.New("at least one admin is required")
+
+// SetRole changes the role of a user. Demoting the last remaining admin is refused.
+func (dao *UserDAO) SetRole(id int, role string) error {
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Role == auth.RoleAdmin && role != auth.RoleAdmin {
+			var admins int64
+			if err := tx.Model(&models.User{}).Where("role = ?", auth.RoleAdmin).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+	})
+}
+
+// EnsureAdmin makes sure the account with the given email exists and is an admin.
+// If it does not exist yet and a password is given, a verified admin account is
+// created; without a password the bootstrap waits until the user has registered.
+// Existing accounts are only promoted once their email address is verified, so
+// registering (or switching to) the admin address is not enough to become admin.
+// Sessions are kept, the role is granted with the next token refresh.
+func (dao *UserDAO) EnsureAdmin(email, password string) (*models.User, error) {
+	user, err := dao.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if password == "" {
+			return nil, nil
+		}
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		user = &models.User{Name: "Administrator", Email: email, Password: hash, Role: auth.RoleAdmin, VerifiedAt: &now}
+		if err := dao.db.Create(user).Error; err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if user.Role != auth.RoleAdmin {
+		if user.VerifiedAt == nil {
+			log.Printf("Not granting the admin role to user %d: email address %s is not verified", user.ID, email)
+			return nil, nil
+		}
+		if err := dao.db.Model(&models.User{}).Where("id = ?", user.ID).Update("role", auth.RoleAdmin).Error; err != nil {
+			return nil, err
+		}
+		user.Role = auth.RoleAdmin
+		log.Printf("Granted the admin role to user %d", user.ID)
+	}
+	return user, nil
+}
+
+// GetByEmail checks if a user with the given email exists (case-insensitive).
 func (dao *UserDAO) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	if err := dao.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := dao.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -66,29 +353,79 @@ func (dao *UserDAO) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (dao *UserDAO) AuthenticateUser(identifier, password string) (*models.User, error) {
+// GetByUsername returns the user with the given username (case-insensitive), or nil.
+func (dao *UserDAO) GetByUsername(username string) (*models.User, error) {
 	var user models.User
-	if err := dao.db.Where("email = ? AND password = ?", identifier, password).First(&user).Error; err != nil {
+	if err := dao.db.Where("LOWER(username) = LOWER(?)", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil // No user found
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// loginQuery matches the identifier against the email if it contains an '@',
+// otherwise against the username. Both comparisons ignore case.
+func (dao *UserDAO) loginQuery(identifier string) *gorm.DB {
+	if strings.Contains(identifier, "@") {
+		return dao.db.Where("LOWER(email) = LOWER(?)", identifier)
+	}
+	return dao.db.Where("LOWER(username) = LOWER(?)", identifier)
+}
+
+// GetByLogin returns the user a login identifier (email or username) refers to, or nil.
+func (dao *UserDAO) GetByLogin(identifier string) (*models.User, error) {
+	var user models.User
+	if err := dao.loginQuery(strings.TrimSpace(identifier)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (dao *UserDAO) GetByIDOrEmail(identifier string) (*models.User, error) {
+// AuthenticateUser looks up the user by email or username and checks the password in Go.
+// It returns nil without an error if the user does not exist or the password is wrong.
+// Legacy plaintext passwords are replaced by a hash after the first successful check.
+func (dao *UserDAO) AuthenticateUser(identifier, password string) (*models.User, error) {
 	var user models.User
+	if err := dao.loginQuery(strings.TrimSpace(identifier)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Spend the same time as a real check so unknown accounts are not distinguishable
+			auth.CheckPassword(dummyPasswordHash, password)
+			return nil, nil // No user found
+		}
+		return nil, err
+	}
 
-	// Try by ID
-	if err := dao.db.First(&user, identifier).Error; err == nil {
-		return &user, nil
+	if !auth.CheckPassword(user.Password, password) {
+		return nil, nil
 	}
 
-	// Fallback: Try by Email
-	if err := dao.db.Where("email = ?", identifier).First(&user).Error; err == nil {
-		return &user, nil
+	if auth.NeedsRehash(user.Password) {
+		if err := dao.SetPassword(user.ID, password); err != nil {
+			// The login itself is valid, the upgrade is retried on the next login
+			log.Printf("[UserDAO.AuthenticateUser] WARN: could not upgrade password hash for user %d: %v", user.ID, err)
+		}
+	}
+	return &user, nil
+}
+
+// GetByIdentifier resolves a numeric user ID, an email address or a username.
+// The rules of ValidateUsername keep the three forms apart, so there is no guessing.
+func (dao *UserDAO) GetByIdentifier(identifier string) (*models.User, error) {
+	var user models.User
+	var query *gorm.DB
+	if id, err := strconv.Atoi(identifier); err == nil {
+		query = dao.db.Where("id = ?", id)
+	} else {
+		query = dao.loginQuery(identifier)
 	}
 
-	return nil, gorm.ErrRecordNotFound
+	if err := query.First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
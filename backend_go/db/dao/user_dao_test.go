@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"backend_go/db/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateEmailResetsVerification(t *testing.T) {
+	database := testDB(t)
+	user := testUser(t, database, "verify")
+	users := NewUserDAO(database, nil)
+	if err := database.Model(&models.User{}).Where("id = ?", user.ID).Update("verified_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing only the case keeps the address verified
+	if err := users.Update(user.ID, UserChanges{Name: user.Name, Email: strings.ToUpper(user.Email)}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := users.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.VerifiedAt == nil {
+		t.Fatal("verification lost on a case-only change")
+	}
+
+	if err := users.Update(user.ID, UserChanges{Name: user.Name, Email: "new-" + user.Email}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err = users.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.VerifiedAt != nil {
+		t.Fatal("new address still counts as verified")
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"backend_go/db/migrations"
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Migrate applies all pending schema migrations.
+func Migrate(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// NewMigrator returns a migrator working on the connection pool of db.
+func NewMigrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB)
+}
@@ -0,0 +1,186 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := load(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	// Versions are consecutive, a gap usually means a file was renamed by accident
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d_%s: expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+}
+
+func TestLoadPairsFilesByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Version != 2 {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[1].Up != "CREATE TABLE b ();" || migrations[1].Down != "DROP TABLE b;" {
+		t.Fatalf("files paired wrongly: %+v", migrations[1])
+	}
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {"0001_first.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad name":     {"first.sql": {Data: []byte("SELECT 1;")}},
+		"name mismatch": {
+			"0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// testSchema connects to TEST_DATABASE_URL with a new, empty schema first on the
+// search path, so the migrations start from scratch; without it the test is skipped.
+func testSchema(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	admin, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+
+	separator := " "
+	if strings.Contains(url, "://") {
+		separator = "?"
+		if strings.Contains(url, "?") {
+			separator = "&"
+		}
+	}
+	db, err := sql.Open("pgx", url+separator+"search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// migrateTo applies the embedded migrations up to and including version.
+func migrateTo(t *testing.T, db *sql.DB, version int) {
+	t.Helper()
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.migrations = m.migrations[:version]
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Rows written by the old service hold Go zero times instead of NULL.
+func TestRecurrenceBackfillIgnoresZeroDates(t *testing.T) {
+	db := testSchema(t)
+	migrateTo(t, db, 3)
+
+	var userID int
+	if err := db.QueryRow("INSERT INTO users (name, email) VALUES ('Test', 'zero@example.com') RETURNING id").Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	const zeroTZ, zero = "0001-01-01 00:00:00+00", "0001-01-01 00:00:00"
+	insert := func(typ, creditStart, creditEnd, zahldatum string) int {
+		t.Helper()
+		var id int
+		err := db.QueryRow(`INSERT INTO haushaltsausgaben (description, type, userid, created_at, changed_at, creditstart, creditend, zahldatum)
+			VALUES ($1, $1, $2, '2024-03-15 10:00:00+00', '2024-03-15 10:00:00+00', $3, $4, $5) RETURNING id`,
+			typ, userID, creditStart, creditEnd, zahldatum).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	invoice := insert("invoice", zeroTZ, zeroTZ, zero)
+	openCredit := insert("credit", "2024-04-01 00:00:00+00", zeroTZ, zero)
+	undatedCredit := insert("credit", zeroTZ, "2025-03-31 00:00:00+00", zero)
+
+	migrateTo(t, db, 4)
+
+	rule := func(id int) (string, string, *string) {
+		t.Helper()
+		var recurrence, start string
+		var end *string
+		err := db.QueryRow("SELECT recurrence, recurrence_start::text, recurrence_end::text FROM haushaltsausgaben WHERE id = $1", id).
+			Scan(&recurrence, &start, &end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return recurrence, start, end
+	}
+	if r, start, end := rule(invoice); r != "once" || start != "2024-03-15" || end != nil {
+		t.Errorf("invoice without zahldatum: %s from %s to %v", r, start, end)
+	}
+	if r, start, end := rule(openCredit); r != "monthly" || start != "2024-04-01" || end != nil {
+		t.Errorf("credit without creditend: %s from %s to %v", r, start, end)
+	}
+	if r, start, end := rule(undatedCredit); start != "2024-03-15" || end == nil || *end != "2025-03-31" {
+		t.Errorf("credit without creditstart: %s from %s to %v", r, start, end)
+	}
+
+	// Databases that ran the old backfill are repaired later on
+	if _, err := db.Exec("UPDATE haushaltsausgaben SET recurrence_start = '0001-01-01' WHERE id = $1", invoice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE haushaltsausgaben SET recurrence_end = '0001-01-01' WHERE id = $1", openCredit); err != nil {
+		t.Fatal(err)
+	}
+	migrateTo(t, db, 9)
+	if _, start, _ := rule(invoice); start != "2024-03-15" {
+		t.Errorf("repaired invoice starts %s", start)
+	}
+	if _, _, end := rule(openCredit); end != nil {
+		t.Errorf("repaired credit ends %s", *end)
+	}
+}
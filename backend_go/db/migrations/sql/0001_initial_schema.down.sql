@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS haushaltsausgaben;
+DROP TABLE IF EXISTS users;
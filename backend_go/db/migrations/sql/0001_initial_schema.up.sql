@@ -0,0 +1,27 @@
+-- Original schema of the service. Existing databases already have these tables,
+-- so everything is created only if it is missing.
+CREATE TABLE IF NOT EXISTS users (
+    id             SERIAL PRIMARY KEY,
+    name           TEXT,
+    email          TEXT,
+    password       TEXT,
+    income         REAL,
+    accountbalance REAL
+);
+
+CREATE TABLE IF NOT EXISTS haushaltsausgaben (
+    id              SERIAL PRIMARY KEY,
+    description     TEXT,
+    valuetotal      NUMERIC,
+    valuerate       NUMERIC,
+    creditstart     TIMESTAMPTZ,
+    creditend       TIMESTAMPTZ,
+    type            VARCHAR,
+    userid          INTEGER,
+    created_at      TIMESTAMPTZ,
+    changed_at      TIMESTAMPTZ,
+    faelligkeitstag VARCHAR,
+    zahldatum       TIMESTAMP
+);
+
+ALTER TABLE haushaltsausgaben ADD COLUMN IF NOT EXISTS receipt BYTEA;
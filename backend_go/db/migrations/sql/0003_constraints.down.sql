@@ -0,0 +1,12 @@
+ALTER TABLE oidc_logins DROP CONSTRAINT IF EXISTS oidc_logins_user_id_fkey;
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS user_identities_user_id_fkey;
+ALTER TABLE api_tokens DROP CONSTRAINT IF EXISTS api_tokens_user_id_fkey;
+ALTER TABLE user_recovery_codes DROP CONSTRAINT IF EXISTS user_recovery_codes_user_id_fkey;
+ALTER TABLE user_totp DROP CONSTRAINT IF EXISTS user_totp_user_id_fkey;
+ALTER TABLE email_verification_tokens DROP CONSTRAINT IF EXISTS email_verification_tokens_user_id_fkey;
+ALTER TABLE password_reset_tokens DROP CONSTRAINT IF EXISTS password_reset_tokens_user_id_fkey;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_id_fkey;
+ALTER TABLE haushaltsausgaben DROP CONSTRAINT IF EXISTS haushaltsausgaben_userid_fkey;
+
+DROP INDEX IF EXISTS idx_haushaltsausgaben_userid;
+DROP INDEX IF EXISTS users_email_lower_key;
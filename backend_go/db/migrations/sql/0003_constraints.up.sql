@@ -0,0 +1,61 @@
+-- Referential integrity and lookup indexes.
+
+-- Email addresses are unique regardless of case. Duplicates from before the
+-- registration check have to be merged by hand first.
+DO $$
+DECLARE
+    duplicates INTEGER;
+BEGIN
+    SELECT COUNT(*) INTO duplicates FROM (
+        SELECT LOWER(email) FROM users WHERE email IS NOT NULL GROUP BY LOWER(email) HAVING COUNT(*) > 1
+    ) d;
+    IF duplicates > 0 THEN
+        RAISE EXCEPTION '% email addresses are used by more than one account, resolve them before migrating', duplicates;
+    END IF;
+END
+$$;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (LOWER(email));
+
+CREATE INDEX IF NOT EXISTS idx_haushaltsausgaben_userid ON haushaltsausgaben (userid);
+
+-- Expenses of deleted accounts go with them. Rows of accounts that no longer
+-- exist are kept; the constraint then only applies to new rows until they are
+-- cleaned up and the constraint is validated by hand.
+ALTER TABLE haushaltsausgaben
+    ADD CONSTRAINT haushaltsausgaben_userid_fkey FOREIGN KEY (userid) REFERENCES users (id) ON DELETE CASCADE NOT VALID;
+DO $$
+BEGIN
+    ALTER TABLE haushaltsausgaben VALIDATE CONSTRAINT haushaltsausgaben_userid_fkey;
+EXCEPTION WHEN foreign_key_violation THEN
+    RAISE WARNING 'haushaltsausgaben contains rows of deleted users, haushaltsausgaben_userid_fkey stays NOT VALID';
+END
+$$;
+
+-- Credentials of accounts that no longer exist are worthless and removed
+DELETE FROM refresh_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM password_reset_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM email_verification_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM user_totp WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM user_recovery_codes WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM api_tokens WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM user_identities WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM oidc_logins WHERE user_id IS NOT NULL AND user_id NOT IN (SELECT id FROM users);
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE password_reset_tokens
+    ADD CONSTRAINT password_reset_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE email_verification_tokens
+    ADD CONSTRAINT email_verification_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_totp
+    ADD CONSTRAINT user_totp_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_recovery_codes
+    ADD CONSTRAINT user_recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE api_tokens
+    ADD CONSTRAINT api_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_identities
+    ADD CONSTRAINT user_identities_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE oidc_logins
+    ADD CONSTRAINT oidc_logins_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+-- The audit log references users without a foreign key, it outlives deleted accounts
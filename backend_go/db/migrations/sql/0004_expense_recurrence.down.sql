@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_haushaltsausgaben_recurrence;
+ALTER TABLE haushaltsausgaben
+    DROP COLUMN IF EXISTS recurrence_end,
+    DROP COLUMN IF EXISTS recurrence_start,
+    DROP COLUMN IF EXISTS recurrence_interval,
+    DROP COLUMN IF EXISTS recurrence;
@@ -0,0 +1,27 @@
+-- Recurrence rules replace the hard-coded meaning of the expense types.
+ALTER TABLE haushaltsausgaben
+    ADD COLUMN recurrence          VARCHAR(16) NOT NULL DEFAULT 'monthly',
+    ADD COLUMN recurrence_interval INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN recurrence_start    DATE,
+    ADD COLUMN recurrence_end      DATE;
+
+-- Same mapping as models.LegacyRecurrence: credits run from creditstart to
+-- creditend, invoices are due once at zahldatum, other one-off expenses in the
+-- month they were entered and fixed costs every month. The service stored
+-- missing dates as Go zero times, so those count as NULL like IsZero there.
+UPDATE haushaltsausgaben SET
+    recurrence = CASE type
+        WHEN 'monthlycosts' THEN 'monthly'
+        WHEN 'credit' THEN 'monthly'
+        ELSE 'once'
+    END,
+    recurrence_interval = CASE WHEN type IN ('monthlycosts', 'credit') THEN 1 ELSE 0 END,
+    recurrence_start = COALESCE(CASE type
+        WHEN 'credit' THEN NULLIF(creditstart, '0001-01-01 00:00:00+00')
+        WHEN 'invoice' THEN NULLIF(zahldatum, '0001-01-01 00:00:00')
+        ELSE NULLIF(created_at, '0001-01-01 00:00:00+00')
+    END, NULLIF(created_at, '0001-01-01 00:00:00+00'), CURRENT_TIMESTAMP)::date,
+    recurrence_end = CASE WHEN type = 'credit' THEN NULLIF(creditend, '0001-01-01 00:00:00+00')::date END;
+
+ALTER TABLE haushaltsausgaben ALTER COLUMN recurrence_start SET NOT NULL;
+CREATE INDEX idx_haushaltsausgaben_recurrence ON haushaltsausgaben (userid, recurrence_start, recurrence_end);
@@ -0,0 +1,5 @@
+ALTER TABLE haushaltsausgaben
+    DROP COLUMN IF EXISTS receipt_thumbnail,
+    DROP COLUMN IF EXISTS receipt_size,
+    DROP COLUMN IF EXISTS receipt_filename,
+    DROP COLUMN IF EXISTS receipt_content_type;
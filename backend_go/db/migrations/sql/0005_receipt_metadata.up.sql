@@ -0,0 +1,9 @@
+-- Metadata of uploaded receipts, so listings never have to read the bytes.
+ALTER TABLE haushaltsausgaben
+    ADD COLUMN receipt_content_type VARCHAR(100),
+    ADD COLUMN receipt_filename     VARCHAR(255),
+    ADD COLUMN receipt_size         BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN receipt_thumbnail    BYTEA;
+
+-- The content type of older receipts is detected when they are downloaded
+UPDATE haushaltsausgaben SET receipt_size = octet_length(receipt) WHERE receipt IS NOT NULL;
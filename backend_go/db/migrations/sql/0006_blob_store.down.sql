@@ -0,0 +1,12 @@
+-- Receipts in the postgres store go back into the expense table; objects in
+-- other stores have to be moved back before reverting.
+UPDATE haushaltsausgaben h
+SET receipt = b.data
+FROM blobs b
+WHERE h.receipt_key = b.key AND h.receipt IS NULL;
+
+DROP TABLE IF EXISTS blobs;
+
+ALTER TABLE haushaltsausgaben
+    DROP COLUMN IF EXISTS receipt_hash,
+    DROP COLUMN IF EXISTS receipt_key;
@@ -0,0 +1,13 @@
+-- Receipts move out of haushaltsausgaben.receipt into a blob store (see package
+-- blobstore). The expense keeps the storage key and a SHA-256 of the content;
+-- existing bytea receipts stay readable until `backend_go receipts migrate` moves them.
+ALTER TABLE haushaltsausgaben
+    ADD COLUMN receipt_key  VARCHAR(255),
+    ADD COLUMN receipt_hash CHAR(64);
+
+-- Objects of the postgres store
+CREATE TABLE blobs (
+    key        VARCHAR(255) PRIMARY KEY,
+    data       BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
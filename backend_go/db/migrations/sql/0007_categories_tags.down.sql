@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS expense_tags;
+DROP TABLE IF EXISTS expense_categories;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,33 @@
+-- User-defined categories (hierarchical, e.g. Living > Rent) and free-form tags.
+CREATE TABLE categories (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    parent_id  INTEGER REFERENCES categories (id) ON DELETE CASCADE,
+    name       VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Names are unique among siblings, ignoring case
+CREATE UNIQUE INDEX idx_categories_sibling_name ON categories (user_id, COALESCE(parent_id, 0), lower(name));
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE tags (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_tags_user_name ON tags (user_id, lower(name));
+
+CREATE TABLE expense_categories (
+    expense_id  INTEGER NOT NULL REFERENCES haushaltsausgaben (id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, category_id)
+);
+CREATE INDEX idx_expense_categories_category_id ON expense_categories (category_id);
+
+CREATE TABLE expense_tags (
+    expense_id INTEGER NOT NULL REFERENCES haushaltsausgaben (id) ON DELETE CASCADE,
+    tag_id     INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, tag_id)
+);
+CREATE INDEX idx_expense_tags_tag_id ON expense_tags (tag_id);
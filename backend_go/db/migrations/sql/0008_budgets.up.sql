@@ -0,0 +1,13 @@
+-- Monthly spending limit per category; it also covers the category's subcategories.
+-- With rollover, the unused amount of earlier months (from start_month on) is added.
+CREATE TABLE budgets (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    amount      NUMERIC NOT NULL CHECK (amount > 0),
+    rollover    BOOLEAN NOT NULL DEFAULT FALSE,
+    start_month DATE NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_budgets_user_category ON budgets (user_id, category_id);
@@ -0,0 +1,2 @@
+-- The repaired dates were wrong, they are not restored.
+SELECT 1;
@@ -0,0 +1,11 @@
+-- Before it ignored Go zero times, 0004 backfilled them as dates in year 1:
+-- invoices without zahldatum started there and credits without creditend ended
+-- there. No real expense starts or ends in year 1, so these rows get the
+-- values 0004 derives now.
+UPDATE haushaltsausgaben
+SET recurrence_start = COALESCE(NULLIF(created_at, '0001-01-01 00:00:00+00'), CURRENT_TIMESTAMP)::date
+WHERE recurrence_start = DATE '0001-01-01';
+
+UPDATE haushaltsausgaben
+SET recurrence_end = NULL
+WHERE recurrence_end = DATE '0001-01-01';
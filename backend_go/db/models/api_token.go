@@ -0,0 +1,30 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIToken is a named personal access token for scripts and automations. Only
+// the hash of the token is stored; Prefix helps users recognize their tokens.
+type APIToken struct {
+	ID         int        `gorm:"primaryKey"`
+	UserID     int        `gorm:"column:user_id;not null;index"`
+	Name       string     `gorm:"column:name;type:varchar(100);not null"`
+	Prefix     string     `gorm:"column:prefix;type:varchar(16);not null"`
+	TokenHash  string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"`
+	Scopes     string     `gorm:"column:scopes;type:text;not null"` // space separated
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ScopeList returns the scopes of the token.
+func (t APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLoginSucceeded           = "login.succeeded"
+	AuditLoginFailed              = "login.failed"
+	AuditSessionRevoked           = "session.revoked"
+	AuditPasswordChanged          = "password.changed"
+	AuditPasswordReset            = "password.reset"
+	AuditTwoFactorEnabled         = "two_factor.enabled"
+	AuditTwoFactorDisabled        = "two_factor.disabled"
+	AuditAPITokenCreated          = "api_token.created"
+	AuditAPITokenRevoked          = "api_token.revoked"
+	AuditRoleChanged              = "user.role_changed"
+	AuditLockoutCleared           = "lockout.cleared"
+	AuditAccountDeletionScheduled = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+	AuditOIDCIdentityLinked       = "oidc.identity_linked"
+	AuditOIDCUserProvisioned      = "oidc.user_provisioned"
+)
+
+// AuditEvent is one entry of the append-only security audit log. ActorID is
+// the user who acted, SubjectID the account affected; either may be unknown.
+type AuditEvent struct {
+	ID        int64     `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	Event     string    `gorm:"column:event;type:varchar(64);not null;index"`
+	ActorID   *int      `gorm:"column:actor_id;index"`
+	SubjectID *int      `gorm:"column:subject_id;index"`
+	IP        string    `gorm:"column:ip;type:varchar(64)"`
+	UserAgent string    `gorm:"column:user_agent;type:text"`
+	Details   string    `gorm:"column:details;type:text"` // JSON object, may be empty
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Budget ist das monatliche Limit eines Benutzers für eine Kategorie samt ihrer
+// Unterkategorien. Mit Rollover wird der ungenutzte Rest der Vormonate ab
+// StartMonth übertragen.
+type Budget struct {
+	ID         int       `gorm:"primaryKey"`
+	UserID     int       `gorm:"column:user_id;not null"`
+	CategoryID int       `gorm:"column:category_id;not null"`
+	Amount     float64   `gorm:"type:numeric;not null"`
+	Rollover   bool      `gorm:"not null"`
+	StartMonth time.Time `gorm:"column:start_month;type:date;not null"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	ChangedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Budget) TableName() string {
+	return "budgets"
+}
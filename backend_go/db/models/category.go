@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Category ordnet Ausgaben einem Zweck zu. Kategorien gehören einem Benutzer und
+// bilden über ParentID einen Baum, z.B. Wohnen > Miete.
+type Category struct {
+	ID        int       `gorm:"primaryKey"`
+	UserID    int       `gorm:"column:user_id;not null"`
+	ParentID  *int      `gorm:"column:parent_id"`
+	Name      string    `gorm:"type:varchar(100);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (Category) TableName() string {
+	return "categories"
+}
+
+// Tag ist ein freies Schlagwort eines Benutzers; Groß-/Kleinschreibung zählt beim
+// Vergleich nicht.
+type Tag struct {
+	ID        int       `gorm:"primaryKey"`
+	UserID    int       `gorm:"column:user_id;not null"`
+	Name      string    `gorm:"type:varchar(50);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
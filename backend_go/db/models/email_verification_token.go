@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EmailVerificationToken is mailed after registration to confirm the address.
+// Only the hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null;index"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+}
+
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"backend_go/recurrence"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Haushaltsausgaben struct {
 	ID              int       `gorm:"primaryKey"`
@@ -15,9 +20,125 @@ type Haushaltsausgaben struct {
 	ChangedAt       time.Time `gorm:"autoUpdateTime"`
 	Faelligkeitstag string    `gorm:"column:faelligkeitstag;type:varchar"`
 	Zahldatum       time.Time `gorm:"type:timestamp"`
-	Receipt         []byte    `gorm:"column:receipt;type:bytea"`
+	Receipt         []byte    `gorm:"column:receipt;type:bytea"` // nur noch alte Belege, neue liegen im BlobStore
+	// Metadaten des Belegs; Listen laden nur diese, nie die Bytes
+	ReceiptKey         string `gorm:"column:receipt_key;type:varchar(255)"` // Schlüssel im BlobStore
+	ReceiptHash        string `gorm:"column:receipt_hash;type:char(64)"`    // SHA-256 des Inhalts (hex)
+	ReceiptContentType string `gorm:"column:receipt_content_type;type:varchar(100)"`
+	ReceiptFilename    string `gorm:"column:receipt_filename;type:varchar(255)"`
+	ReceiptSize        int64  `gorm:"column:receipt_size"`
+	ReceiptThumbnail   []byte `gorm:"column:receipt_thumbnail;type:bytea"` // JPEG, nur für Bilder
+	// Wiederholung der Ausgabe, siehe Paket recurrence
+	Recurrence         string     `gorm:"column:recurrence;type:varchar(16)"`
+	RecurrenceInterval int        `gorm:"column:recurrence_interval"` // Monate zwischen zwei Fälligkeiten
+	RecurrenceStart    time.Time  `gorm:"column:recurrence_start;type:date"`
+	RecurrenceEnd      *time.Time `gorm:"column:recurrence_end;type:date"`
+	// Zuordnung zu Kategorien und Tags (Tabellen expense_categories, expense_tags)
+	Categories []Category `gorm:"many2many:expense_categories;joinForeignKey:ExpenseID;joinReferences:CategoryID"`
+	Tags       []Tag      `gorm:"many2many:expense_tags;joinForeignKey:ExpenseID;joinReferences:TagID"`
 }
 
 func (Haushaltsausgaben) TableName() string {
 	return "haushaltsausgaben"
 }
+
+// HasReceipt meldet, ob ein Beleg hinterlegt ist, auch wenn die Bytes nicht geladen wurden
+func (h *Haushaltsausgaben) HasReceipt() bool {
+	return h.ReceiptSize > 0 || len(h.Receipt) > 0
+}
+
+// DueAmount ist der Betrag, der bei einer Fälligkeit anfällt: die Rate, falls es
+// eine gibt (z.B. bei Krediten), sonst der Gesamtbetrag
+func (h *Haushaltsausgaben) DueAmount() float64 {
+	if h.ValueRate > 0 {
+		return h.ValueRate
+	}
+	return h.ValueTotal
+}
+
+// Recurring ist wahr für wiederkehrende Ausgaben; sie zählen als Fixkosten
+func (h *Haushaltsausgaben) Recurring() bool {
+	return h.RecurrenceRule().Months() > 0
+}
+
+// Version identifiziert den Stand des Eintrags für optimistisches Sperren (If-Match).
+// Sie ergibt sich aus changed_at in Mikrosekunden, der Genauigkeit von Postgres.
+func (h *Haushaltsausgaben) Version() string {
+	if h.ChangedAt.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(h.ChangedAt.UnixMicro(), 10)
+}
+
+// RecurrenceRule liefert die Wiederholungsregel; der Fälligkeitstag kommt aus Faelligkeitstag
+func (h *Haushaltsausgaben) RecurrenceRule() recurrence.Rule {
+	rule := recurrence.Rule{
+		Frequency: recurrence.Frequency(h.Recurrence),
+		Interval:  h.RecurrenceInterval,
+		Start:     h.RecurrenceStart,
+		End:       h.RecurrenceEnd,
+	}
+	// Ungültige Altwerte (z.B. leer oder "Monatsanfang") fallen auf den Tag des Starts zurück
+	if day, err := strconv.Atoi(strings.TrimSpace(h.Faelligkeitstag)); err == nil && day >= 1 && day <= 31 {
+		rule.DayOfMonth = day
+	}
+	return rule
+}
+
+// SetRecurrence übernimmt eine Regel in die Spalten des Eintrags
+func (h *Haushaltsausgaben) SetRecurrence(rule recurrence.Rule) {
+	h.Recurrence = string(rule.Frequency)
+	h.RecurrenceInterval = rule.Months()
+	h.RecurrenceStart = rule.Start
+	h.RecurrenceEnd = rule.End
+}
+
+// FollowsLegacyRecurrence meldet, ob die gespeicherte Regel noch die aus Typ und
+// Datumsfeldern abgeleitete ist (siehe LegacyRecurrence). Verglichen wird nur das
+// Datum, die Spalten recurrence_start und recurrence_end speichern keine Uhrzeit.
+func (h *Haushaltsausgaben) FollowsLegacyRecurrence() bool {
+	var legacy Haushaltsausgaben
+	legacy.SetRecurrence(LegacyRecurrence(h.Type, h.CreatedAt, h.CreditStart, h.CreditEnd, h.Zahldatum))
+	if h.Recurrence != legacy.Recurrence || h.RecurrenceInterval != legacy.RecurrenceInterval {
+		return false
+	}
+	if !sameDate(h.RecurrenceStart, legacy.RecurrenceStart) {
+		return false
+	}
+	if h.RecurrenceEnd == nil || legacy.RecurrenceEnd == nil {
+		return h.RecurrenceEnd == nil && legacy.RecurrenceEnd == nil
+	}
+	return sameDate(*h.RecurrenceEnd, *legacy.RecurrenceEnd)
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// LegacyRecurrence leitet die Regel aus der bisherigen Bedeutung der Typen ab,
+// für Clients, die keine Regel mitschicken (entspricht Migration 0004)
+func LegacyRecurrence(typ string, created, creditStart, creditEnd, zahldatum time.Time) recurrence.Rule {
+	switch typ {
+	case "monthlycosts":
+		return recurrence.Rule{Frequency: recurrence.Monthly, Start: created}
+	case "credit":
+		rule := recurrence.Rule{Frequency: recurrence.Monthly, Start: creditStart}
+		if creditStart.IsZero() {
+			rule.Start = created
+		}
+		if !creditEnd.IsZero() {
+			end := creditEnd
+			rule.End = &end
+		}
+		return rule
+	case "invoice":
+		if zahldatum.IsZero() {
+			return recurrence.Rule{Frequency: recurrence.Once, Start: created}
+		}
+		return recurrence.Rule{Frequency: recurrence.Once, Start: zahldatum}
+	default:
+		return recurrence.Rule{Frequency: recurrence.Once, Start: created}
+	}
+}
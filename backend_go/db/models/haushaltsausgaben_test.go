@@ -0,0 +1,49 @@
+package models
+
+import (
+	"backend_go/recurrence"
+	"testing"
+	"time"
+)
+
+func TestFollowsLegacyRecurrence(t *testing.T) {
+	created := time.Date(2025, 1, 10, 14, 30, 0, 0, time.UTC)
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	credit := func() Haushaltsausgaben {
+		h := Haushaltsausgaben{Type: "credit", CreatedAt: created, CreditStart: start, CreditEnd: end}
+		h.SetRecurrence(LegacyRecurrence(h.Type, h.CreatedAt, h.CreditStart, h.CreditEnd, h.Zahldatum))
+		return h
+	}
+
+	h := credit()
+	if !h.FollowsLegacyRecurrence() {
+		t.Fatal("derived rule not recognized")
+	}
+
+	// The date columns drop the time of day
+	h = Haushaltsausgaben{Type: "monthlycosts", CreatedAt: created}
+	h.SetRecurrence(recurrence.Rule{Frequency: recurrence.Monthly, Start: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)})
+	if !h.FollowsLegacyRecurrence() {
+		t.Fatal("rule with the date of created_at not recognized")
+	}
+
+	h = credit()
+	shorter := end.AddDate(0, -6, 0)
+	h.RecurrenceEnd = &shorter
+	if h.FollowsLegacyRecurrence() {
+		t.Fatal("rule with another end treated as derived")
+	}
+
+	h = credit()
+	h.RecurrenceEnd = nil
+	if h.FollowsLegacyRecurrence() {
+		t.Fatal("open-ended rule treated as derived from a credit with an end")
+	}
+
+	h = credit()
+	h.SetRecurrence(recurrence.Rule{Frequency: recurrence.EveryNMonths, Interval: 3, Start: start, End: &end})
+	if h.FollowsLegacyRecurrence() {
+		t.Fatal("quarterly rule treated as derived")
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginThrottle counts failed logins for one key, e.g. "ip:203.0.113.7" or
+// "account:tobi@example.com". It lives in Postgres so lockouts survive restarts.
+type LoginThrottle struct {
+	Key           string     `gorm:"column:key;type:varchar(320);primaryKey"`
+	Failures      int        `gorm:"column:failures;not null;default:0"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at;not null"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use token mailed to a user who forgot the password.
+// Only the hash of the token is stored.
+type PasswordResetToken struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null;index"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RefreshToken is one link in a rotating chain of refresh tokens. All tokens
+// issued from the same login share a FamilyID, which also identifies the session.
+type RefreshToken struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null;index"`
+	FamilyID  string     `gorm:"column:family_id;type:varchar(64);not null;index"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"`
+	UserAgent string     `gorm:"column:user_agent;type:text"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UserTOTP holds the TOTP secret of a user, encrypted with the server key.
+// EnabledAt stays nil until the user proved possession with a first code.
+type UserTOTP struct {
+	UserID          int        `gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	SecretEncrypted []byte     `gorm:"column:secret_encrypted;type:bytea;not null"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+	EnabledAt       *time.Time `gorm:"column:enabled_at"`
+	LastUsedStep    int64      `gorm:"column:last_used_step;not null;default:0"`
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode is a hashed one-time code that replaces a TOTP code once.
+type RecoveryCode struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null;index"`
+	CodeHash  string     `gorm:"column:code_hash;type:varchar(64);not null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
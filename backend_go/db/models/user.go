@@ -1,10 +1,24 @@
 package models
 
+import "time"
+
 type User struct {
 	ID             int     `json:"id"`
 	Name           string  `json:"name"`
+	Username       *string `gorm:"column:username;type:varchar(32)" json:"username"` // unique, case-insensitive
 	Email          string  `json:"email"`
-	Password       string  `json:"password"`
+	Password       string  `json:"-"` // bcrypt hash, never serialized
 	Income         float32 `json:"income"`
 	Accountbalance float32 `json:"accountbalance"`
+	Role           string  `gorm:"column:role;type:varchar(16);not null;default:member" json:"role"`
+	// VerifiedAt is set once the email address was confirmed; unverified users are read-only
+	VerifiedAt *time.Time `gorm:"column:verified_at" json:"-"`
+	// DeletionScheduledAt is set when the user asked to delete the account; the
+	// account and all its data are purged once this point in time has passed
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at" json:"-"`
+}
+
+// IsVerified reports whether the user confirmed the email address.
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect
+// provider. The pair of issuer and subject identifies the external account.
+type UserIdentity struct {
+	ID          int        `gorm:"primaryKey"`
+	UserID      int        `gorm:"column:user_id;not null;index"`
+	Issuer      string     `gorm:"column:issuer;type:varchar(255);not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Subject     string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Email       string     `gorm:"column:email;type:varchar(255)"` // as reported by the provider at the last login
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCLogin tracks one login attempt at an external provider. StateHash ties the
+// callback to the attempt; after the callback the user receives a single-use
+// login code that the frontend exchanges for a session.
+type OIDCLogin struct {
+	ID            int        `gorm:"primaryKey"`
+	Provider      string     `gorm:"column:provider;type:varchar(64);not null"`
+	StateHash     string     `gorm:"column:state_hash;type:varchar(64);not null;uniqueIndex"`
+	Nonce         string     `gorm:"column:nonce;type:varchar(64);not null"`
+	CodeVerifier  string     `gorm:"column:code_verifier;type:varchar(128);not null"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at;not null;index"`
+	UserID        *int       `gorm:"column:user_id;index"`
+	LoginCodeHash *string    `gorm:"column:login_code_hash;type:varchar(64);uniqueIndex"`
+	CompletedAt   *time.Time `gorm:"column:completed_at"`
+}
+
+func (OIDCLogin) TableName() string {
+	return "oidc_logins"
+}
@@ -0,0 +1,273 @@
+// Package einvoice reads German e-invoices: XRechnung in either syntax (UN/CEFACT
+// CII or OASIS UBL) and ZUGFeRD/Factur-X PDFs, which embed a CII document.
+package einvoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Syntaxes of the invoice XML.
+const (
+	FormatCII = "CII"
+	FormatUBL = "UBL"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("not an XRechnung or ZUGFeRD invoice")
+	ErrNoInvoiceData     = errors.New("PDF does not contain ZUGFeRD/Factur-X invoice data")
+	ErrInvalidInvoice    = errors.New("invoice is missing mandatory data")
+)
+
+// Invoice holds the parts of an e-invoice that are needed for an expense.
+type Invoice struct {
+	Format    string
+	Number    string
+	IssueDate time.Time
+	DueDate   *time.Time // payment due date, nil if the invoice has none
+	Seller    string
+	Currency  string
+	Total     float64 // amount due for payment (BT-115)
+}
+
+// Parse reads an XRechnung XML document or a ZUGFeRD/Factur-X PDF.
+func Parse(data []byte) (*Invoice, error) {
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		xmlData, err := ExtractXML(data)
+		if err != nil {
+			return nil, err
+		}
+		data = xmlData
+	}
+	return ParseXML(data)
+}
+
+// ParseXML reads a CII or UBL invoice document.
+func ParseXML(data []byte) (*Invoice, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice *Invoice
+	switch {
+	case root.Local == "CrossIndustryInvoice":
+		invoice, err = parseCII(data)
+	case root.Local == "Invoice" && strings.Contains(root.Space, "ubl:schema:xsd:Invoice-2"):
+		invoice, err = parseUBL(data)
+	case root.Local == "CreditNote":
+		return nil, fmt.Errorf("%w: credit notes cannot be imported as expenses", ErrUnsupportedFormat)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := invoice.validate(); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// rootElement returns the name of the document element.
+func rootElement(data []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, ErrUnsupportedFormat
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func (i *Invoice) validate() error {
+	var missing []string
+	if i.Seller == "" {
+		missing = append(missing, "seller")
+	}
+	if i.IssueDate.IsZero() {
+		missing = append(missing, "issue date")
+	}
+	if i.Total <= 0 {
+		missing = append(missing, "positive amount due")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidInvoice, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ciiInvoice maps the used parts of a UN/CEFACT CrossIndustryInvoice. Element
+// names are matched without namespace.
+type ciiInvoice struct {
+	Document struct {
+		ID        string  `xml:"ID"`
+		IssueDate ciiDate `xml:"IssueDateTime>DateTimeString"`
+	} `xml:"ExchangedDocument"`
+	Transaction struct {
+		Seller     string `xml:"ApplicableHeaderTradeAgreement>SellerTradeParty>Name"`
+		Settlement struct {
+			Currency     string `xml:"InvoiceCurrencyCode"`
+			PaymentTerms []struct {
+				DueDate ciiDate `xml:"DueDateDateTime>DateTimeString"`
+			} `xml:"SpecifiedTradePaymentTerms"`
+			Summation struct {
+				GrandTotal amount `xml:"GrandTotalAmount"`
+				DuePayable amount `xml:"DuePayableAmount"`
+			} `xml:"SpecifiedTradeSettlementHeaderMonetarySummation"`
+		} `xml:"ApplicableHeaderTradeSettlement"`
+	} `xml:"SupplyChainTradeTransaction"`
+}
+
+type ciiDate struct {
+	Format string `xml:"format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+func parseCII(data []byte) (*Invoice, error) {
+	var doc ciiInvoice
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, xmlError(err)
+	}
+	settlement := doc.Transaction.Settlement
+	invoice := &Invoice{
+		Format:   FormatCII,
+		Number:   strings.TrimSpace(doc.Document.ID),
+		Seller:   strings.TrimSpace(doc.Transaction.Seller),
+		Currency: strings.TrimSpace(settlement.Currency),
+		Total:    float64(settlement.Summation.DuePayable),
+	}
+	if invoice.Total == 0 {
+		invoice.Total = float64(settlement.Summation.GrandTotal)
+	}
+
+	var err error
+	if invoice.IssueDate, err = doc.Document.IssueDate.time(); err != nil {
+		return nil, err
+	}
+	for _, terms := range settlement.PaymentTerms {
+		if terms.DueDate.Value == "" {
+			continue
+		}
+		due, err := terms.DueDate.time()
+		if err != nil {
+			return nil, err
+		}
+		invoice.DueDate = &due
+		break
+	}
+	return invoice, nil
+}
+
+// time parses a CII date; XRechnung only allows format 102 (YYYYMMDD).
+func (d ciiDate) time() (time.Time, error) {
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d.Format != "" && d.Format != "102" {
+		return time.Time{}, fmt.Errorf("%w: unsupported date format %s", ErrInvalidInvoice, d.Format)
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidInvoice, value)
+	}
+	return t, nil
+}
+
+// ublInvoice maps the used parts of an OASIS UBL 2.1 Invoice.
+type ublInvoice struct {
+	ID        string `xml:"ID"`
+	IssueDate string `xml:"IssueDate"`
+	DueDate   string `xml:"DueDate"`
+	Currency  string `xml:"DocumentCurrencyCode"`
+	Supplier  struct {
+		Name             string `xml:"PartyName>Name"`
+		RegistrationName string `xml:"PartyLegalEntity>RegistrationName"`
+	} `xml:"AccountingSupplierParty>Party"`
+	PaymentMeans []struct {
+		DueDate string `xml:"PaymentDueDate"`
+	} `xml:"PaymentMeans"`
+	Totals struct {
+		Payable amount `xml:"PayableAmount"`
+	} `xml:"LegalMonetaryTotal"`
+}
+
+func parseUBL(data []byte) (*Invoice, error) {
+	var doc ublInvoice
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, xmlError(err)
+	}
+	invoice := &Invoice{
+		Format:   FormatUBL,
+		Number:   strings.TrimSpace(doc.ID),
+		Seller:   strings.TrimSpace(doc.Supplier.Name),
+		Currency: strings.TrimSpace(doc.Currency),
+		Total:    float64(doc.Totals.Payable),
+	}
+	if invoice.Seller == "" {
+		invoice.Seller = strings.TrimSpace(doc.Supplier.RegistrationName)
+	}
+
+	var err error
+	if invoice.IssueDate, err = ublDate(doc.IssueDate); err != nil {
+		return nil, err
+	}
+	dueDate := doc.DueDate
+	for _, means := range doc.PaymentMeans {
+		if dueDate == "" {
+			dueDate = means.DueDate
+		}
+	}
+	if dueDate != "" {
+		due, err := ublDate(dueDate)
+		if err != nil {
+			return nil, err
+		}
+		invoice.DueDate = &due
+	}
+	return invoice, nil
+}
+
+func ublDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidInvoice, value)
+	}
+	return t, nil
+}
+
+func xmlError(err error) error {
+	if errors.Is(err, ErrInvalidInvoice) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+}
+
+// amount is a decimal element like <ram:DuePayableAmount>119.00</ram:DuePayableAmount>.
+type amount float64
+
+func (a *amount) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid amount %q", ErrInvalidInvoice, s)
+	}
+	*a = amount(v)
+	return nil
+}
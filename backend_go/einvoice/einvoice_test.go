@@ -0,0 +1,129 @@
+package einvoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseFixtures(t *testing.T) {
+	ciiDue := date(2025, time.April, 17)
+	ublDue := date(2025, time.May, 16)
+	tests := []struct {
+		fixture string
+		want    Invoice
+	}{
+		{"xrechnung-cii.xml", Invoice{
+			Format: FormatCII, Number: "RE-2025-0417", IssueDate: date(2025, time.April, 3), DueDate: &ciiDue,
+			Seller: "Stadtwerke Musterstadt GmbH", Currency: "EUR", Total: 79.25,
+		}},
+		{"xrechnung-ubl.xml", Invoice{
+			Format: FormatUBL, Number: "2025-INV-88", IssueDate: date(2025, time.May, 2), DueDate: &ublDue,
+			Seller: "Telekommunikation Beispiel AG", Currency: "EUR", Total: 39.95,
+		}},
+		// ZUGFeRD/Factur-X: the CII fixture embedded as factur-x.xml
+		{"zugferd.pdf", Invoice{
+			Format: FormatCII, Number: "RE-2025-0417", IssueDate: date(2025, time.April, 3), DueDate: &ciiDue,
+			Seller: "Stadtwerke Musterstadt GmbH", Currency: "EUR", Total: 79.25,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := Parse(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format != tt.want.Format || got.Number != tt.want.Number || !got.IssueDate.Equal(tt.want.IssueDate) ||
+				got.Seller != tt.want.Seller || got.Currency != tt.want.Currency || got.Total != tt.want.Total {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+			if got.DueDate == nil || !got.DueDate.Equal(*tt.want.DueDate) {
+				t.Errorf("due date %v, want %v", got.DueDate, tt.want.DueDate)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		fixture string
+		err     error
+	}{
+		{"plain.pdf", ErrNoInvoiceData},
+		{"ubl-credit-note.xml", ErrUnsupportedFormat},
+		{"cii-missing-amount.xml", ErrInvalidInvoice},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(readFixture(t, tt.fixture)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.fixture, err, tt.err)
+		}
+	}
+	if _, err := Parse([]byte("Rechnung als Text")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("plain text: got %v", err)
+	}
+}
+
+func TestExtractXMLFromPDF(t *testing.T) {
+	data, err := ExtractXML(readFixture(t, "zugferd.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(readFixture(t, "xrechnung-cii.xml")) {
+		t.Fatal("embedded XML differs from the fixture")
+	}
+}
+
+// flateStreamPDF builds a minimal PDF body with one compressed stream object per
+// entry of contents.
+func flateStreamPDF(t *testing.T, dict string, contents ...[]byte) []byte {
+	t.Helper()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	for i, content := range contents {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		if _, err := w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		fmt.Fprintf(&pdf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", i+1, dict, compressed.Len())
+		pdf.Write(compressed.Bytes())
+		pdf.WriteString("\nendstream\nendobj\n")
+	}
+	pdf.WriteString("%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractXMLLimitsDecompressedSize(t *testing.T) {
+	bombs := make([][]byte, 30)
+	for i := range bombs {
+		bombs[i] = make([]byte, 2<<20)
+	}
+	if _, err := ExtractXML(flateStreamPDF(t, "", bombs...)); !errors.Is(err, ErrInvalidInvoice) {
+		t.Fatalf("expected the decompression limit to hit, got %v", err)
+	}
+
+	// Embedded files are checked first, so the XML is found before the other streams are inflated
+	pdf := append(flateStreamPDF(t, "", bombs...), flateStreamPDF(t, "/Type /EmbeddedFile", readFixture(t, "xrechnung-cii.xml"))...)
+	if _, err := ExtractXML(pdf); err != nil {
+		t.Fatalf("embedded XML not found: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package einvoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxXMLSize limits the decompressed size of an embedded invoice.
+const maxXMLSize = 10 << 20
+
+// maxDecodedTotal limits the decompressed bytes of all streams of one PDF, so
+// many small streams that inflate to large ones cannot exhaust the memory.
+const maxDecodedTotal = 4 * maxXMLSize
+
+var errDecodeLimit = fmt.Errorf("%w: PDF streams decompress to more than %d bytes", ErrInvalidInvoice, maxDecodedTotal)
+
+// ExtractXML returns the invoice XML embedded in a ZUGFeRD/Factur-X PDF. Rather
+// than resolving the object tree, it looks at the streams one at a time, those
+// marked as embedded files first; this also copes with cross-reference streams.
+// Encrypted PDFs are not supported.
+func ExtractXML(pdf []byte) ([]byte, error) {
+	streams := pdfStreams(pdf)
+	budget := int64(maxDecodedTotal)
+	for _, embedded := range []bool{true, false} {
+		for _, s := range streams {
+			if s.embeddedFile() != embedded {
+				continue
+			}
+			data, err := s.decoded(&budget)
+			if err != nil {
+				return nil, err
+			}
+			if isInvoiceXML(data) {
+				return data, nil
+			}
+		}
+	}
+	return nil, ErrNoInvoiceData
+}
+
+type pdfStream struct {
+	dict []byte
+	body []byte
+}
+
+// pdfStreams finds all "obj << … >> stream … endstream" sections.
+func pdfStreams(pdf []byte) []pdfStream {
+	var streams []pdfStream
+	rest, offset := pdf, 0
+	for {
+		i := bytes.Index(rest, []byte("stream"))
+		if i < 0 {
+			return streams
+		}
+		start := offset + i
+		rest, offset = rest[i+len("stream"):], start+len("stream")
+
+		// "endstream" also contains "stream"; only keyword starts count
+		if start >= 3 && string(pdf[start-3:start]) == "end" {
+			continue
+		}
+		bodyStart := offset
+		if bytes.HasPrefix(pdf[bodyStart:], []byte("\r\n")) {
+			bodyStart += 2
+		} else if bytes.HasPrefix(pdf[bodyStart:], []byte("\n")) {
+			bodyStart++
+		} else {
+			continue
+		}
+		end := bytes.Index(pdf[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+		objStart := bytes.LastIndex(pdf[:start], []byte("obj"))
+		if objStart < 0 {
+			continue
+		}
+		body := bytes.TrimRight(pdf[bodyStart:bodyStart+end], "\r\n")
+		streams = append(streams, pdfStream{dict: pdf[objStart:start], body: body})
+		rest, offset = pdf[bodyStart+end+len("endstream"):], bodyStart+end+len("endstream")
+	}
+}
+
+func (s pdfStream) embeddedFile() bool {
+	return bytes.Contains(s.dict, []byte("/EmbeddedFile"))
+}
+
+// decoded returns the stream content, or nil if it cannot be decoded; only
+// FlateDecode and unfiltered streams are supported, which is what ZUGFeRD
+// generators use for the XML. Decompressed bytes are taken from budget, once
+// it is used up decoding fails with errDecodeLimit.
+func (s pdfStream) decoded(budget *int64) ([]byte, error) {
+	if !bytes.Contains(s.dict, []byte("/Filter")) {
+		return s.body, nil
+	}
+	if !bytes.Contains(s.dict, []byte("/FlateDecode")) {
+		return nil, nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(s.body))
+	if err != nil {
+		return nil, nil
+	}
+	defer r.Close()
+	limit := min(int64(maxXMLSize), *budget)
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	*budget -= int64(len(data))
+	if *budget < 0 {
+		return nil, errDecodeLimit
+	}
+	if (err != nil && !errors.Is(err, io.ErrUnexpectedEOF)) || len(data) > maxXMLSize {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// isInvoiceXML reports whether data is a CII or UBL invoice document.
+func isInvoiceXML(data []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		return false
+	}
+	root, err := rootElement(trimmed)
+	return err == nil && (root.Local == "CrossIndustryInvoice" || root.Local == "Invoice" || root.Local == "CreditNote")
+}
@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox writes messages to a writer or directory instead of delivering them.
+// It is meant for local development, where reset and verification links can
+// be copied from the log or the outbox folder.
+type Outbox struct {
+	mu  sync.Mutex
+	out io.Writer
+	dir string
+}
+
+// NewStdoutOutbox prints every message to stdout.
+func NewStdoutOutbox() *Outbox {
+	return &Outbox{out: os.Stdout}
+}
+
+// NewFileOutbox stores every message as a separate .eml file in dir.
+func NewFileOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// Send implements Mailer.
+func (o *Outbox) Send(msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	if o.dir == "" {
+		_, err := fmt.Fprintf(o.out, "----- outgoing mail -----\n%s-------------------------\n", content)
+		return err
+	}
+
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102-150405.000000000"))
+	return os.WriteFile(filepath.Join(o.dir, name), []byte(content), 0o600)
+}
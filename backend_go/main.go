@@ -2,9 +2,15 @@ package main
 
 import (
 	"backend_go/router"
+	"os"
 )
 
 func main() {
+	// Administrative subcommands, e.g. `backend_go unlock tobi@example.com`
+	if len(os.Args) > 1 && runCommand(os.Args[1:]) {
+		return
+	}
+
 	r := router.SetupRouter()
 	r.Run(":8000") // Run the backend on port 8080
 }
@@ -0,0 +1,44 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ProvidersFromEnv reads the providers listed in OIDC_PROVIDERS (comma separated
+// names). Each provider NAME is configured with
+//
+//	OIDC_NAME_ISSUER         issuer URL (required)
+//	OIDC_NAME_CLIENT_ID      client ID (required)
+//	OIDC_NAME_CLIENT_SECRET  optional for public clients
+//	OIDC_NAME_DISPLAY_NAME   label for the login button
+//	OIDC_NAME_SCOPES         space separated, default "openid email profile"
+//	OIDC_NAME_AUTO_PROVISION "false" to only allow existing accounts
+//
+// The redirect URL is <apiBaseURL>/auth/oidc/<name>/callback.
+func ProvidersFromEnv(apiBaseURL string) (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := Config{
+			Name:          name,
+			DisplayName:   os.Getenv(prefix + "DISPLAY_NAME"),
+			Issuer:        os.Getenv(prefix + "ISSUER"),
+			ClientID:      os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:        strings.Fields(os.Getenv(prefix + "SCOPES")),
+			RedirectURL:   strings.TrimSuffix(apiBaseURL, "/") + "/auth/oidc/" + name + "/callback",
+			AutoProvision: os.Getenv(prefix+"AUTO_PROVISION") != "false",
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers[name] = NewProvider(cfg, nil)
+	}
+	return providers, nil
+}
@@ -0,0 +1,102 @@
+package oidc
+
+import (
+	"backend_go/oidc/oidctest"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// login runs the browser part of the flow against the mock issuer and returns
+// the authorization code from the redirect.
+func login(t *testing.T, p *Provider, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	t.Helper()
+	iss, err := oidctest.New("leviathan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(iss.Close)
+	p := NewProvider(Config{
+		Name:        "mock",
+		Issuer:      iss.URL,
+		ClientID:    "leviathan",
+		RedirectURL: "http://localhost:8000/auth/oidc/mock/callback",
+	}, nil)
+	return p, iss
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, iss := newTestProvider(t)
+	iss.SetUser(oidctest.User{Subject: "abc-123", Email: "anna@example.com", EmailVerified: true, Name: "Anna"})
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := login(t, p, "state-1", "nonce-1", verifier)
+
+	identity, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Issuer: iss.URL, Subject: "abc-123", Email: "anna@example.com", EmailVerified: true, Name: "Anna"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+
+	// Codes are single use
+	if _, err := p.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("expected a redeemed code to be rejected")
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	p, _ := newTestProvider(t)
+	verifier, _ := NewCodeVerifier()
+	other, _ := NewCodeVerifier()
+	code := login(t, p, "state", "nonce", verifier)
+
+	if _, err := p.Exchange(context.Background(), code, other, "nonce"); err == nil {
+		t.Fatal("expected PKCE verification to fail")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	p, _ := newTestProvider(t)
+	verifier, _ := NewCodeVerifier()
+	code := login(t, p, "state", "nonce", verifier)
+
+	_, err := p.Exchange(context.Background(), code, verifier, "another-nonce")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package receipt checks uploaded receipts and renders previews of images.
+package receipt
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Allowed content types, detected from the content and never taken from the client.
+const (
+	TypePDF  = "application/pdf"
+	TypePNG  = "image/png"
+	TypeJPEG = "image/jpeg"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+	// XML only comes from e-invoice imports, DetectType never returns it
+	TypeXML = "application/xml"
+)
+
+// ThumbnailSize is the maximum width and height of a preview in pixels.
+const ThumbnailSize = 256
+
+// maxPixels protects against decompression bombs: larger images get no preview.
+const maxPixels = 50_000_000
+
+var (
+	ErrUnsupportedType = errors.New("only PDF, PNG, JPEG, GIF and WebP receipts are allowed")
+	ErrNoThumbnail     = errors.New("no preview available for this receipt")
+)
+
+// DetectType sniffs the content type of a receipt and rejects everything that
+// is not a PDF or an image.
+func DetectType(data []byte) (string, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case TypePDF, TypePNG, TypeJPEG, TypeGIF, TypeWebP:
+		return contentType, nil
+	default:
+		return "", ErrUnsupportedType
+	}
+}
+
+// Extension returns the usual file extension for a content type.
+func Extension(contentType string) string {
+	switch contentType {
+	case TypePDF:
+		return ".pdf"
+	case TypePNG:
+		return ".png"
+	case TypeJPEG:
+		return ".jpg"
+	case TypeGIF:
+		return ".gif"
+	case TypeWebP:
+		return ".webp"
+	case TypeXML:
+		return ".xml"
+	default:
+		return ".bin"
+	}
+}
+
+// Inline reports whether browsers may display the receipt directly. Everything
+// else, e.g. XML that could carry scripts, is served as a download.
+func Inline(contentType string) bool {
+	switch contentType {
+	case TypePDF, TypePNG, TypeJPEG, TypeGIF, TypeWebP:
+		return true
+	default:
+		return false
+	}
+}
+
+// Thumbnail renders a JPEG preview that fits into ThumbnailSize. PDFs and
+// WebP images (no decoder in the standard library) return ErrNoThumbnail.
+func Thumbnail(data []byte, contentType string) ([]byte, error) {
+	var decode func([]byte) (image.Image, error)
+	var decodeConfig func([]byte) (image.Config, error)
+	switch contentType {
+	case TypePNG:
+		decode = func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) }
+	case TypeJPEG:
+		decode = func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) }
+	case TypeGIF:
+		decode = func(b []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return gif.DecodeConfig(bytes.NewReader(b)) }
+	default:
+		return nil, ErrNoThumbnail
+	}
+
+	cfg, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrNoThumbnail
+	}
+	src, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleDown shrinks img to fit into size×size by averaging the source pixels
+// of each target pixel. Transparent areas become white, as JPEG has no alpha.
+func scaleDown(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/b.Dx())
+		} else {
+			w, h = max(1, w*size/b.Dy()), size
+		}
+	}
+
+	// Flatten onto white first, so averaging works on opaque pixels
+	flat := image.NewRGBA(b)
+	draw.Draw(flat, b, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					o := flat.PixOffset(sx, sy)
+					r += uint64(flat.Pix[o])
+					g += uint64(flat.Pix[o+1])
+					bl += uint64(flat.Pix[o+2])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: 255})
+		}
+	}
+	return dst
+}
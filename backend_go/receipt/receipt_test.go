@@ -0,0 +1,77 @@
+package receipt
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"pdf", []byte("%PDF-1.7\n1 0 obj"), TypePDF, nil},
+		{"png", testPNG(t, 2, 2), TypePNG, nil},
+		{"html", []byte("<html><script>alert(1)</script></html>"), "", ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", ErrUnsupportedType},
+		{"empty", nil, "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		got, err := DetectType(tt.data)
+		if got != tt.want || err != tt.err {
+			t.Errorf("%s: got %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestThumbnailFitsIntoBox(t *testing.T) {
+	thumb, err := Thumbnail(testPNG(t, 800, 400), TypePNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != ThumbnailSize || cfg.Height != ThumbnailSize/2 {
+		t.Fatalf("thumbnail is %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	thumb, err := Thumbnail(testPNG(t, 40, 30), TypePNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if cfg.Width != 40 || cfg.Height != 30 {
+		t.Fatalf("thumbnail is %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestThumbnailForPDF(t *testing.T) {
+	if _, err := Thumbnail([]byte("%PDF-1.7"), TypePDF); err != ErrNoThumbnail {
+		t.Fatalf("expected ErrNoThumbnail, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Package recurrence expands recurring expenses into their concrete due dates.
+// Rules work on whole months: an expense recurs in the month of Start and then
+// every Interval months, up to and including the month of End.
+package recurrence
+
+import (
+	"errors"
+	"time"
+)
+
+// Frequency names how often an expense recurs.
+type Frequency string
+
+const (
+	Once         Frequency = "once"
+	Monthly      Frequency = "monthly"
+	Quarterly    Frequency = "quarterly"
+	Yearly       Frequency = "yearly"
+	EveryNMonths Frequency = "every_n_months"
+)
+
+// MaxInterval limits EveryNMonths to ten years between occurrences.
+const MaxInterval = 120
+
+var (
+	ErrUnknownFrequency = errors.New("unknown recurrence frequency")
+	ErrInvalidInterval  = errors.New("recurrence interval must be between 1 and 120 months")
+	ErrMissingStart     = errors.New("recurrence start is required")
+	ErrEndBeforeStart   = errors.New("recurrence end must not be before its start")
+	ErrInvalidDay       = errors.New("due day must be between 1 and 31")
+)
+
+// Rule describes when an expense is due.
+type Rule struct {
+	Frequency Frequency
+	// Interval is the number of months between occurrences for EveryNMonths
+	Interval int
+	// Start is the first occurrence; its month anchors all later ones
+	Start time.Time
+	// End is optional; no occurrence falls into a later month
+	End *time.Time
+	// DayOfMonth is the due day, clamped to the length of the month.
+	// 0 uses the day of Start.
+	DayOfMonth int
+}
+
+// Months returns the number of months between two occurrences, 0 for Once.
+func (r Rule) Months() int {
+	switch r.Frequency {
+	case Monthly:
+		return 1
+	case Quarterly:
+		return 3
+	case Yearly:
+		return 12
+	case EveryNMonths:
+		return r.Interval
+	default:
+		return 0
+	}
+}
+
+// Validate checks that the rule can be expanded.
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Once, Monthly, Quarterly, Yearly:
+	case EveryNMonths:
+		if r.Interval < 1 || r.Interval > MaxInterval {
+			return ErrInvalidInterval
+		}
+	default:
+		return ErrUnknownFrequency
+	}
+	if r.Start.IsZero() {
+		return ErrMissingStart
+	}
+	if r.End != nil && monthIndex(*r.End) < monthIndex(r.Start) {
+		return ErrEndBeforeStart
+	}
+	if r.DayOfMonth < 0 || r.DayOfMonth > 31 {
+		return ErrInvalidDay
+	}
+	return nil
+}
+
+// Occurrences returns the due dates of the rule in [from, to), in order.
+// Invalid rules have no occurrences.
+func (r Rule) Occurrences(from, to time.Time) []time.Time {
+	if r.Validate() != nil || !from.Before(to) {
+		return nil
+	}
+	from, to = from.UTC(), to.UTC()
+
+	first := monthIndex(r.Start)
+	last := monthIndex(to)
+	if r.End != nil && monthIndex(*r.End) < last {
+		last = monthIndex(*r.End)
+	}
+
+	step := r.Months()
+	if step == 0 {
+		if due := r.dueDate(first); first <= last && !due.Before(from) && due.Before(to) {
+			return []time.Time{due}
+		}
+		return nil
+	}
+
+	// Jump to the first occurrence in or after the month of from
+	month := first
+	if start := monthIndex(from); start > first {
+		month = first + (start-first+step-1)/step*step
+	}
+
+	var dates []time.Time
+	for ; month <= last; month += step {
+		due := r.dueDate(month)
+		if !due.Before(to) {
+			break
+		}
+		if !due.Before(from) {
+			dates = append(dates, due)
+		}
+	}
+	return dates
+}
+
+// dueDate returns the due date in the given month (see monthIndex).
+func (r Rule) dueDate(month int) time.Time {
+	year, m := month/12, time.Month(month%12+1)
+	day := r.DayOfMonth
+	if day == 0 {
+		day = r.Start.Day()
+	}
+	if days := daysIn(year, m); day > days {
+		day = days
+	}
+	return time.Date(year, m, day, 0, 0, 0, 0, time.UTC)
+}
+
+// monthIndex counts months since year 0, so month arithmetic is plain addition.
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// MonthRange returns the first day of the month and of the following month.
+func MonthRange(month time.Time) (time.Time, time.Time) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
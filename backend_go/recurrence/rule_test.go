@@ -0,0 +1,122 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestOccurrences(t *testing.T) {
+	end := date(2025, 6, 10)
+	tests := []struct {
+		name     string
+		rule     Rule
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "monthly within a month",
+			rule: Rule{Frequency: Monthly, Start: date(2024, 1, 15), DayOfMonth: 3},
+			from: date(2025, 3, 1), to: date(2025, 4, 1),
+			want: []time.Time{date(2025, 3, 3)},
+		},
+		{
+			name: "monthly not before its start month",
+			rule: Rule{Frequency: Monthly, Start: date(2025, 3, 20), DayOfMonth: 1},
+			from: date(2025, 1, 1), to: date(2025, 5, 1),
+			want: []time.Time{date(2025, 3, 1), date(2025, 4, 1)},
+		},
+		{
+			name: "end month is included",
+			rule: Rule{Frequency: Monthly, Start: date(2025, 4, 1), End: &end, DayOfMonth: 28},
+			from: date(2025, 1, 1), to: date(2026, 1, 1),
+			want: []time.Time{date(2025, 4, 28), date(2025, 5, 28), date(2025, 6, 28)},
+		},
+		{
+			name: "quarterly anchored at start",
+			rule: Rule{Frequency: Quarterly, Start: date(2024, 2, 10)},
+			from: date(2025, 1, 1), to: date(2026, 1, 1),
+			want: []time.Time{date(2025, 2, 10), date(2025, 5, 10), date(2025, 8, 10), date(2025, 11, 10)},
+		},
+		{
+			name: "yearly skips other months",
+			rule: Rule{Frequency: Yearly, Start: date(2020, 7, 1)},
+			from: date(2025, 1, 1), to: date(2025, 7, 1),
+			want: nil,
+		},
+		{
+			name: "every two months",
+			rule: Rule{Frequency: EveryNMonths, Interval: 2, Start: date(2025, 1, 5)},
+			from: date(2025, 2, 1), to: date(2025, 8, 1),
+			want: []time.Time{date(2025, 3, 5), date(2025, 5, 5), date(2025, 7, 5)},
+		},
+		{
+			name: "due day clamped to short months",
+			rule: Rule{Frequency: Monthly, Start: date(2024, 1, 31)},
+			from: date(2024, 2, 1), to: date(2024, 5, 1),
+			want: []time.Time{date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30)},
+		},
+		{
+			name: "once",
+			rule: Rule{Frequency: Once, Start: date(2025, 3, 14)},
+			from: date(2025, 3, 1), to: date(2025, 4, 1),
+			want: []time.Time{date(2025, 3, 14)},
+		},
+		{
+			name: "once outside the range",
+			rule: Rule{Frequency: Once, Start: date(2025, 3, 14)},
+			from: date(2025, 3, 15), to: date(2025, 4, 1),
+			want: nil,
+		},
+		{
+			name: "range boundaries are half-open",
+			rule: Rule{Frequency: Monthly, Start: date(2025, 1, 1), DayOfMonth: 15},
+			from: date(2025, 1, 15), to: date(2025, 3, 15),
+			want: []time.Time{date(2025, 1, 15), date(2025, 2, 15)},
+		},
+		{
+			name: "invalid rule",
+			rule: Rule{Frequency: EveryNMonths, Start: date(2025, 1, 1)},
+			from: date(2025, 1, 1), to: date(2026, 1, 1),
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Occurrences(tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	start := date(2025, 5, 1)
+	before := date(2025, 4, 30)
+	tests := map[string]struct {
+		rule Rule
+		want error
+	}{
+		"valid":            {Rule{Frequency: Quarterly, Start: start}, nil},
+		"unknown":          {Rule{Frequency: "weekly", Start: start}, ErrUnknownFrequency},
+		"missing interval": {Rule{Frequency: EveryNMonths, Start: start}, ErrInvalidInterval},
+		"missing start":    {Rule{Frequency: Monthly}, ErrMissingStart},
+		"end before start": {Rule{Frequency: Monthly, Start: start, End: &before}, ErrEndBeforeStart},
+		"invalid day":      {Rule{Frequency: Monthly, Start: start, DayOfMonth: 32}, ErrInvalidDay},
+	}
+	for name, tt := range tests {
+		if err := tt.rule.Validate(); err != tt.want {
+			t.Errorf("%s: got %v, want %v", name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextUserIDKey is the gin context key holding the authenticated user's ID.
+const ContextUserIDKey = "userID"
+
+// ContextSessionIDKey is the gin context key holding the session (refresh token family) ID.
+const ContextSessionIDKey = "sessionID"
+
+// ContextRoleKey is the gin context key holding the caller's role.
+const ContextRoleKey = "role"
+
+// ContextScopesKey is the gin context key holding the scopes of an API token.
+// It is not set for login sessions, which are not restricted.
+const ContextScopesKey = "scopes"
+
+// RequireAuth checks the bearer token in the Authorization header and stores
+// the caller's user ID in the request context. Requests without a valid token,
+// or whose session was revoked, are rejected with 401.
+// Personal API tokens are only accepted if apiTokens is not nil; routes that
+// accept them should restrict access further with RequireScope.
+func RequireAuth(tokens *auth.TokenManager, sessions *dao.RefreshTokenDAO, apiTokens *dao.APITokenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="backend_go"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
+			return
+		}
+
+		if auth.IsAPIToken(token) {
+			if apiTokens == nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API tokens cannot be used for this endpoint"})
+				return
+			}
+			apiToken, err := apiTokens.Authenticate(token)
+			if errors.Is(err, dao.ErrAPITokenInvalid) {
+				c.Header("WWW-Authenticate", `Bearer realm="backend_go", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API token"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API token"})
+				return
+			}
+			c.Set(ContextUserIDKey, apiToken.UserID)
+			// API tokens never carry admin rights
+			c.Set(ContextRoleKey, auth.RoleMember)
+			c.Set(ContextScopesKey, apiToken.ScopeList())
+			c.Next()
+			return
+		}
+
+		claims, err := tokens.ParseAccessToken(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="backend_go", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		// A revoked session invalidates its access tokens immediately
+		if claims.SessionID != "" {
+			active, err := sessions.IsSessionActive(claims.SessionID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session"})
+				return
+			}
+			if !active {
+				c.Header("WWW-Authenticate", `Bearer realm="backend_go", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				return
+			}
+		}
+
+		userID, _ := claims.UserID()
+		c.Set(ContextUserIDKey, userID)
+		c.Set(ContextSessionIDKey, claims.SessionID)
+		role := claims.Role
+		if role == "" {
+			// Tokens issued before roles existed
+			role = auth.RoleMember
+		}
+		c.Set(ContextRoleKey, role)
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated user's ID set by RequireAuth, or 0 if the
+// request did not pass through the middleware.
+func UserID(c *gin.Context) int {
+	return c.GetInt(ContextUserIDKey)
+}
+
+// SessionID returns the session ID of the access token, if any.
+func SessionID(c *gin.Context) string {
+	return c.GetString(ContextSessionIDKey)
+}
+
+// Principal returns the authenticated caller as an auth.Principal.
+func Principal(c *gin.Context) auth.Principal {
+	return auth.Principal{UserID: UserID(c), Role: c.GetString(ContextRoleKey)}
+}
+
+// RequireRole only lets callers with one of the given roles through.
+// It must run after RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(ContextRoleKey)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
+
+// RequireScope lets API tokens through only if they were granted scope.
+// Login sessions always pass. It must run after RequireAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, isAPIToken := c.Get(ContextScopesKey)
+		if !isAPIToken {
+			c.Next()
+			return
+		}
+		scopes, _ := value.([]string)
+		for _, granted := range scopes {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="backend_go", error="insufficient_scope", scope="`+scope+`"`)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token lacks scope " + scope})
+	}
+}
+
+// RequireVerified rejects write requests of users that have not confirmed their
+// email address yet. Reads stay allowed so new users can look around.
+// It must run after RequireAuth.
+func RequireVerified(userDAO *dao.UserDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		user, err := userDAO.GetByID(UserID(c))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User no longer exists"})
+			return
+		}
+		if !user.IsVerified() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"backend_go/auth"
+	"backend_go/db"
+	"backend_go/db/dao"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testTokens = auth.NewTokenManager([]byte("0123456789abcdef0123456789abcdef"), time.Minute, time.Hour)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testDB connects to TEST_DATABASE_URL and migrates it; without it the test is skipped.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// serve runs one request through the handlers and a final handler that echoes
+// what the middleware stored in the context.
+func serve(method, authorization string, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := gin.New()
+	handlers = append(handlers, func(c *gin.Context) {
+		c.String(http.StatusOK, "%d %s %s", UserID(c), SessionID(c), c.GetString(ContextRoleKey))
+	})
+	r.Handle(method, "/", handlers...)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireAuthRejectsMissingOrInvalidTokens(t *testing.T) {
+	requireAuth := RequireAuth(testTokens, nil, nil)
+	mfa, _, _ := testTokens.IssueMFAToken(1)
+	for name, header := range map[string]string{
+		"missing":   "",
+		"basic":     "Basic dXNlcjpwYXNz",
+		"empty":     "Bearer ",
+		"garbage":   "Bearer not.a.token",
+		"mfa token": "Bearer " + mfa,
+	} {
+		w := serve(http.MethodGet, header, requireAuth)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d", name, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: WWW-Authenticate missing", name)
+		}
+	}
+}
+
+func TestRequireAuthSetsCaller(t *testing.T) {
+	token, _, err := testTokens.IssueAccessToken(42, "", auth.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := serve(http.MethodGet, "Bearer "+token, RequireAuth(testTokens, nil, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "42  admin" {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+
+	// Tokens from before roles existed count as members
+	token, _, _ = testTokens.IssueAccessToken(42, "", "")
+	w = serve(http.MethodGet, "Bearer "+token, RequireAuth(testTokens, nil, nil))
+	if w.Body.String() != "42  member" {
+		t.Fatalf("body %q", w.Body.String())
+	}
+}
+
+func TestRequireAuthRejectsRevokedSession(t *testing.T) {
+	database := testDB(t)
+	users := dao.NewUserDAO(database, nil)
+	user, err := users.Create("Session Test", "", fmt.Sprintf("session-%d@example.com", time.Now().UnixNano()), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { users.Delete(user.ID) })
+
+	sessions := dao.NewRefreshTokenDAO(database)
+	_, session, err := sessions.Create(user.ID, "test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, _ := testTokens.IssueAccessToken(user.ID, session.FamilyID, auth.RoleMember)
+	requireAuth := RequireAuth(testTokens, sessions, nil)
+
+	if w := serve(http.MethodGet, "Bearer "+token, requireAuth); w.Code != http.StatusOK {
+		t.Fatalf("active session: status %d", w.Code)
+	}
+	if err := sessions.RevokeFamily(session.FamilyID); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(http.MethodGet, "Bearer "+token, requireAuth); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked session: status %d", w.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withScopes stands in for RequireAuth accepting an API token.
+func withScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextUserIDKey, 1)
+		c.Set(ContextScopesKey, scopes)
+		c.Next()
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	requireWrite := RequireScope(auth.ScopeExpensesWrite)
+	if w := serve(http.MethodGet, "", asUser(1), requireWrite); w.Code != http.StatusOK {
+		t.Errorf("login session: status %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "", withScopes(auth.ScopeExpensesRead, auth.ScopeExpensesWrite), requireWrite); w.Code != http.StatusOK {
+		t.Errorf("granted scope: status %d", w.Code)
+	}
+	for name, handler := range map[string]gin.HandlerFunc{
+		"other scope": withScopes(auth.ScopeExpensesRead),
+		"no scopes":   withScopes(),
+	} {
+		w := serve(http.MethodGet, "", handler, requireWrite)
+		if w.Code != http.StatusForbidden || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: status %d, WWW-Authenticate %q", name, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestRequireAuthRejectsAPITokensWhereNotAllowed(t *testing.T) {
+	// The token is never looked up, so no database is needed
+	w := serve(http.MethodGet, "Bearer "+auth.APITokenPrefix+"whatever", RequireAuth(testTokens, nil, nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d", w.Code)
+	}
+}
+
+func TestRequireAuthAcceptsAPITokens(t *testing.T) {
+	database := testDB(t)
+	users := dao.NewUserDAO(database, nil)
+	user, err := users.Create("API Token Test", "", fmt.Sprintf("apitoken-%d@example.com", time.Now().UnixNano()), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { users.Delete(user.ID) })
+
+	apiTokens := dao.NewAPITokenDAO(database)
+	plain, token, err := apiTokens.Create(user.ID, "test", []string{auth.ScopeExpensesRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireAuth := RequireAuth(testTokens, nil, apiTokens)
+
+	// API tokens never carry a session or admin rights
+	if w := serve(http.MethodGet, "Bearer "+plain, requireAuth); w.Code != http.StatusOK || w.Body.String() != fmt.Sprintf("%d  %s", user.ID, auth.RoleMember) {
+		t.Fatalf("valid token: %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodPost, "Bearer "+plain, requireAuth, RequireScope(auth.ScopeExpensesWrite)); w.Code != http.StatusForbidden {
+		t.Fatalf("missing scope: status %d", w.Code)
+	}
+	if err := apiTokens.Revoke(user.ID, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(http.MethodGet, "Bearer "+plain, requireAuth); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: status %d", w.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// asUser stands in for RequireAuth.
+func asUser(id int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextUserIDKey, id)
+		c.Next()
+	}
+}
+
+func TestRequireVerifiedLetsReadsThrough(t *testing.T) {
+	// Reads never look up the user, so no database is needed
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		if w := serve(method, "", asUser(1), RequireVerified(nil)); w.Code != http.StatusOK {
+			t.Errorf("%s: status %d", method, w.Code)
+		}
+	}
+}
+
+func TestRequireVerifiedBlocksWritesUntilVerified(t *testing.T) {
+	database := testDB(t)
+	users := dao.NewUserDAO(database, nil)
+	user, err := users.Create("Verify Test", "", fmt.Sprintf("verify-%d@example.com", time.Now().UnixNano()), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { users.Delete(user.ID) })
+	requireVerified := RequireVerified(users)
+
+	if w := serve(http.MethodPost, "", asUser(user.ID), requireVerified); w.Code != http.StatusForbidden {
+		t.Fatalf("unverified write: status %d", w.Code)
+	}
+	if err := database.Model(&models.User{}).Where("id = ?", user.ID).Update("verified_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if w := serve(method, "", asUser(user.ID), requireVerified); w.Code != http.StatusOK {
+			t.Errorf("verified %s: status %d", method, w.Code)
+		}
+	}
+	// Tokens of deleted accounts stay valid until they expire
+	if w := serve(http.MethodPost, "", asUser(-1), requireVerified); w.Code != http.StatusUnauthorized {
+		t.Fatalf("deleted user: status %d", w.Code)
+	}
+}
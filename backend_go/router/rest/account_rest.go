@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterAccountRoutes registers the self-service endpoints under /users/me:
+// profile, personal data export and account deletion. A deletion only takes
+// effect after deletionGrace; zero deletes immediately.
+func RegisterAccountRoutes(r *gin.Engine, userDAO *dao.UserDAO, expenseDAO *dao.HaushaltsausgabenDAO, sessionDAO *dao.RefreshTokenDAO, apiTokenDAO *dao.APITokenDAO, audit *AuditLog, deletionGrace time.Duration, requireAuth gin.HandlerFunc) {
+	meRoutes := r.Group("/users/me", requireAuth)
+	{
+		meRoutes.GET("", getMe(userDAO))
+		meRoutes.GET("/export", exportMe(userDAO, expenseDAO))
+		meRoutes.DELETE("", deleteMe(userDAO, sessionDAO, apiTokenDAO, audit, deletionGrace))
+	}
+}
+
+func getMe(userDAO *dao.UserDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userDAO.GetByID(middleware.UserID(c))
+		if err != nil || user == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusOK, toUserResponse(user))
+	}
+}
+
+// exportMe sends a ZIP archive with everything stored about the caller.
+func exportMe(userDAO *dao.UserDAO, expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userDAO.GetByID(middleware.UserID(c))
+		if err != nil || user == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		expenses, err := expenseDAO.GetByUserIDWithReceipts(middleware.Principal(c), user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+			return
+		}
+
+		now := time.Now()
+		filename := fmt.Sprintf("leviathan-export-%d-%s.zip", user.ID, now.Format("20060102"))
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		if err := writeUserExport(c.Writer, user, expenses, now); err != nil {
+			// The headers are already sent, so the client only sees a broken archive
+			c.Error(err)
+		}
+	}
+}
+
+// deleteMe deletes the caller's account after confirming the password. With a
+// grace period the account is only scheduled for deletion and all sessions and
+// API tokens are revoked; logging in again before the deadline cancels the deletion.
+func deleteMe(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, apiTokenDAO *dao.APITokenDAO, audit *AuditLog, deletionGrace time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password confirmation is required"})
+			return
+		}
+
+		userID := middleware.UserID(c)
+		ok, err := userDAO.CheckPassword(userID, input.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify password"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return
+		}
+
+		if deletionGrace <= 0 {
+			if err := userDAO.Delete(userID); err != nil {
+				respondWithDeletionError(c, err)
+				return
+			}
+			audit.Record(c, models.AuditAccountDeleted, userID, userID, nil)
+			c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+			return
+		}
+
+		deleteAt := time.Now().Add(deletionGrace)
+		if err := userDAO.ScheduleDeletion(userID, deleteAt); err != nil {
+			respondWithDeletionError(c, err)
+			return
+		}
+		if err := sessionDAO.RevokeAllForUser(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+		if err := apiTokenDAO.RevokeAllForUser(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API tokens"})
+			return
+		}
+
+		audit.Record(c, models.AuditAccountDeletionScheduled, userID, userID, gin.H{"delete_at": deleteAt})
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":   "Account scheduled for deletion, log in again before the deadline to cancel",
+			"delete_at": deleteAt,
+		})
+	}
+}
+
+func respondWithDeletionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, dao.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	}
+}
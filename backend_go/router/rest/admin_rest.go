@@ -0,0 +1,170 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterAdminRoutes registers system endpoints that are only available to admins.
+func RegisterAdminRoutes(r *gin.Engine, userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, auditDAO *dao.AuditDAO, audit *AuditLog, requireAuth, requireAdmin gin.HandlerFunc) {
+	adminRoutes := r.Group("/admin", requireAuth, requireAdmin)
+	{
+		adminRoutes.PUT("/users/:id/role", setUserRole(userDAO, sessionDAO, audit))
+		adminRoutes.GET("/lockouts", getLockouts(throttleDAO))
+		adminRoutes.DELETE("/lockouts/:key", clearLockout(throttleDAO, audit))
+		adminRoutes.GET("/audit-events", getAuditEvents(auditDAO))
+	}
+}
+
+// setUserRole changes the role of a user. The user's sessions are revoked so
+// tokens carrying the old role stop working.
+func setUserRole(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		var input struct {
+			Role string `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !auth.ValidRole(input.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+
+		if err := userDAO.SetRole(id, input.Role); err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			case errors.Is(err, dao.ErrLastAdmin):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			}
+			return
+		}
+
+		if err := sessionDAO.RevokeAllForUser(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+		audit.Record(c, models.AuditRoleChanged, middleware.UserID(c), id, gin.H{"role": input.Role})
+
+		c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": input.Role})
+	}
+}
+
+// getLockouts lists the login throttle keys that are locked right now.
+func getLockouts(throttleDAO *dao.LoginThrottleDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		throttles, err := throttleDAO.ListLocked()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lockouts"})
+			return
+		}
+
+		result := make([]gin.H, 0, len(throttles))
+		for _, t := range throttles {
+			result = append(result, gin.H{
+				"key":                 t.Key,
+				"failures":            t.Failures,
+				"locked_until":        t.LockedUntil,
+				"retry_after_seconds": int(time.Until(*t.LockedUntil).Seconds()),
+			})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// clearLockout removes a lockout. The key is used as listed by GET /admin/lockouts,
+// e.g. "account:alice@example.com" or "ip:203.0.113.7".
+func clearLockout(throttleDAO *dao.LoginThrottleDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := throttleDAO.Clear(c.Param("key")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear lockout"})
+			return
+		}
+		audit.Record(c, models.AuditLockoutCleared, middleware.UserID(c), 0, gin.H{"key": c.Param("key")})
+		c.JSON(http.StatusOK, gin.H{"message": "Lockout cleared"})
+	}
+}
+
+// getAuditEvents queries the audit log, newest first. Supported filters are
+// event, actor_id, subject_id, ip, since and until (RFC 3339) as well as limit
+// and before (an event ID) for paging.
+func getAuditEvents(auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := dao.AuditFilter{
+			Event: c.Query("event"),
+			IP:    c.Query("ip"),
+		}
+
+		var err error
+		if filter.ActorID, err = queryInt(c, "actor_id"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor_id"})
+			return
+		}
+		if filter.SubjectID, err = queryInt(c, "subject_id"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject_id"})
+			return
+		}
+		if filter.Limit, err = queryInt(c, "limit"); err != nil || filter.Limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if before := c.Query("before"); before != "" {
+			if filter.BeforeID, err = strconv.ParseInt(before, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+				return
+			}
+		}
+		if filter.Since, err = queryTime(c, "since"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected RFC 3339"})
+			return
+		}
+		if filter.Until, err = queryTime(c, "until"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until, expected RFC 3339"})
+			return
+		}
+
+		events, err := auditDAO.Query(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+			return
+		}
+		c.JSON(http.StatusOK, toAuditEventResponses(events))
+	}
+}
+
+// queryInt reads an optional integer query parameter; a missing one is 0.
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// queryTime reads an optional RFC 3339 query parameter; a missing one is the zero time.
+func queryTime(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
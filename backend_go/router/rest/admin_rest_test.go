@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetAuditEventsRejectsInvalidFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// The DAO is nil: invalid filters must be rejected before the query
+	r.GET("/admin/audit", getAuditEvents(nil))
+
+	for _, query := range []string{
+		"actor_id=x",
+		"subject_id=1.5",
+		"limit=-1",
+		"before=abc",
+		"since=2024-01-01",
+		"until=yesterday",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterAPITokenRoutes registers the management of personal API tokens. Only
+// login sessions may manage tokens, so requireAuth must not accept API tokens.
+func RegisterAPITokenRoutes(r *gin.Engine, apiTokenDAO *dao.APITokenDAO, audit *AuditLog, requireAuth gin.HandlerFunc) {
+	tokenRoutes := r.Group("/users/me/tokens", requireAuth)
+	{
+		tokenRoutes.GET("", listAPITokens(apiTokenDAO))
+		tokenRoutes.POST("", createAPIToken(apiTokenDAO, audit))
+		tokenRoutes.DELETE("/:tokenId", revokeAPIToken(apiTokenDAO, audit))
+	}
+}
+
+func listAPITokens(apiTokenDAO *dao.APITokenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokens, err := apiTokenDAO.ListForUser(middleware.UserID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API tokens"})
+			return
+		}
+
+		result := make([]APITokenResponse, 0, len(tokens))
+		for i := range tokens {
+			result = append(result, toAPITokenResponse(&tokens[i]))
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// createAPIToken issues a new token. The plaintext token is part of this
+// response only and cannot be retrieved later.
+func createAPIToken(apiTokenDAO *dao.APITokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input CreateAPITokenRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, scope := range input.Scopes {
+			if !auth.ValidScope(scope) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + scope})
+				return
+			}
+		}
+
+		var expiresAt *time.Time
+		if input.ExpiresInDays > 0 {
+			t := time.Now().AddDate(0, 0, input.ExpiresInDays)
+			expiresAt = &t
+		}
+
+		plain, token, err := apiTokenDAO.Create(middleware.UserID(c), input.Name, input.Scopes, expiresAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+			return
+		}
+		audit.Record(c, models.AuditAPITokenCreated, token.UserID, token.UserID, gin.H{"token_id": token.ID, "name": token.Name, "scopes": input.Scopes})
+
+		c.JSON(http.StatusCreated, gin.H{
+			"token":   plain,
+			"details": toAPITokenResponse(token),
+			"message": "Store this token now, it will not be shown again",
+		})
+	}
+}
+
+func revokeAPIToken(apiTokenDAO *dao.APITokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("tokenId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+			return
+		}
+
+		err = apiTokenDAO.Revoke(middleware.UserID(c), id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+			return
+		}
+		audit.Record(c, models.AuditAPITokenRevoked, middleware.UserID(c), middleware.UserID(c), gin.H{"token_id": id})
+		c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+	}
+}
@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"encoding/json"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLog records security relevant events together with the client address
+// and user agent of the current request.
+type AuditLog struct {
+	auditDAO *dao.AuditDAO
+}
+
+// NewAuditLog creates an AuditLog writing to auditDAO.
+func NewAuditLog(auditDAO *dao.AuditDAO) *AuditLog {
+	return &AuditLog{auditDAO: auditDAO}
+}
+
+// Record stores an event. actorID is the acting user and subjectID the affected
+// account, 0 means unknown. Failures are logged but never fail the request.
+func (a *AuditLog) Record(c *gin.Context, event string, actorID, subjectID int, details gin.H) {
+	if a == nil {
+		return
+	}
+	entry := &models.AuditEvent{
+		Event:     event,
+		ActorID:   optionalID(actorID),
+		SubjectID: optionalID(subjectID),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err != nil {
+			log.Printf("[Audit] ERROR encoding details of %s: %v", event, err)
+		} else {
+			entry.Details = string(data)
+		}
+	}
+	if err := a.auditDAO.Record(entry); err != nil {
+		log.Printf("[Audit] ERROR recording %s: %v", event, err)
+	}
+}
+
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
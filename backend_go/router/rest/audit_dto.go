@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"backend_go/db/models"
+	"encoding/json"
+	"time"
+)
+
+// AuditEventResponse is the API representation of an audit log entry.
+type AuditEventResponse struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Event     string          `json:"event"`
+	ActorID   *int            `json:"actor_id"`
+	SubjectID *int            `json:"subject_id"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+// toAuditEventResponses maps audit log entries to their API representation.
+func toAuditEventResponses(events []models.AuditEvent) []AuditEventResponse {
+	result := make([]AuditEventResponse, 0, len(events))
+	for _, e := range events {
+		resp := AuditEventResponse{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			Event:     e.Event,
+			ActorID:   e.ActorID,
+			SubjectID: e.SubjectID,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+		}
+		if e.Details != "" {
+			resp.Details = json.RawMessage(e.Details)
+		}
+		result = append(result, resp)
+	}
+	return result
+}
@@ -0,0 +1,240 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/budget"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BudgetRequest ist der Body für das Anlegen und Ändern eines Budgets. startmonth
+// (YYYY-MM) ist optional: beim Anlegen gilt dann der aktuelle Monat, beim Ändern
+// bleibt der bisherige (und damit der angesammelte Rollover). Die Kategorie lässt
+// sich nach dem Anlegen nicht mehr ändern.
+type BudgetRequest struct {
+	CategoryID int     `json:"categoryid"`
+	Amount     float64 `json:"amount"`
+	Rollover   bool    `json:"rollover"`
+	StartMonth string  `json:"startmonth"`
+}
+
+// BudgetResponse ist die API-Darstellung eines Budgets
+type BudgetResponse struct {
+	ID         int     `json:"ID"`
+	CategoryID int     `json:"CategoryID"`
+	Amount     float64 `json:"Amount"`
+	Rollover   bool    `json:"Rollover"`
+	StartMonth string  `json:"StartMonth"`
+}
+
+// BudgetLineResponse ist eine Zeile des Monatsreports. Planned ist das Limit plus
+// der übertragene Rest der Vormonate, Status ist ok, warning (ab 80 %) oder exceeded.
+type BudgetLineResponse struct {
+	BudgetID     int      `json:"BudgetID"`
+	CategoryID   int      `json:"CategoryID"`
+	CategoryPath []string `json:"CategoryPath"`
+	Limit        float64  `json:"Limit"`
+	Carryover    float64  `json:"Carryover"`
+	Planned      float64  `json:"Planned"`
+	Spent        float64  `json:"Spent"`
+	Remaining    float64  `json:"Remaining"`
+	Status       string   `json:"Status"`
+}
+
+// BudgetReportResponse ist der Report eines Monats samt Summen
+type BudgetReportResponse struct {
+	Month     string               `json:"Month"`
+	Lines     []BudgetLineResponse `json:"Lines"`
+	Planned   float64              `json:"Planned"`
+	Spent     float64              `json:"Spent"`
+	Remaining float64              `json:"Remaining"`
+}
+
+// RegisterBudgetRoutes registriert die Monatsbudgets je Kategorie und den Report
+// GET /budgets/:month (YYYY-MM)
+func RegisterBudgetRoutes(r *gin.Engine, budgetDAO *dao.BudgetDAO, categoryDAO *dao.CategoryDAO, requireAuth, requireVerified gin.HandlerFunc) {
+	canRead := middleware.RequireScope(auth.ScopeExpensesRead)
+	canWrite := middleware.RequireScope(auth.ScopeExpensesWrite)
+
+	budgetRoutes := r.Group("/budgets", requireAuth, requireVerified)
+	{
+		budgetRoutes.GET("/", canRead, listBudgets(budgetDAO))
+		budgetRoutes.GET("/:month", canRead, getBudgetReport(budgetDAO, categoryDAO))
+		budgetRoutes.POST("/", canWrite, createBudget(budgetDAO))
+		budgetRoutes.PUT("/:id", canWrite, updateBudget(budgetDAO))
+		budgetRoutes.DELETE("/:id", canWrite, deleteBudget(budgetDAO))
+	}
+}
+
+// respondWithBudgetError übersetzt DAO-Fehler in HTTP-Statuscodes
+func respondWithBudgetError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+	case errors.Is(err, dao.ErrInvalidBudget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, dao.ErrBudgetExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// bindBudgetRequest liest den Body und den Startmonat; ohne startmonth ist er
+// nil. Bei Fehlern ist die Antwort bereits geschrieben.
+func bindBudgetRequest(c *gin.Context) (BudgetRequest, *time.Time, bool) {
+	var input BudgetRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return input, nil, false
+	}
+	if input.StartMonth == "" {
+		return input, nil, true
+	}
+	startMonth, err := time.Parse("2006-01", input.StartMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid startmonth, expected YYYY-MM"})
+		return input, nil, false
+	}
+	return input, &startMonth, true
+}
+
+func listBudgets(budgetDAO *dao.BudgetDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budgets, err := budgetDAO.ListBudgets(middleware.UserID(c))
+		if err != nil {
+			respondWithBudgetError(c, err, "Failed to fetch budgets")
+			return
+		}
+		result := make([]BudgetResponse, 0, len(budgets))
+		for i := range budgets {
+			result = append(result, toBudgetResponse(&budgets[i]))
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func createBudget(budgetDAO *dao.BudgetDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		input, startMonth, ok := bindBudgetRequest(c)
+		if !ok {
+			return
+		}
+		if startMonth == nil {
+			now := time.Now().UTC()
+			startMonth = &now
+		}
+		budget, err := budgetDAO.CreateBudget(middleware.UserID(c), input.CategoryID, input.Amount, input.Rollover, *startMonth)
+		if err != nil {
+			respondWithBudgetError(c, err, "Failed to create budget")
+			return
+		}
+		c.JSON(http.StatusCreated, toBudgetResponse(budget))
+	}
+}
+
+func updateBudget(budgetDAO *dao.BudgetDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+			return
+		}
+		input, startMonth, ok := bindBudgetRequest(c)
+		if !ok {
+			return
+		}
+		budget, err := budgetDAO.UpdateBudget(middleware.UserID(c), id, input.Amount, input.Rollover, startMonth)
+		if err != nil {
+			respondWithBudgetError(c, err, "Failed to update budget")
+			return
+		}
+		c.JSON(http.StatusOK, toBudgetResponse(budget))
+	}
+}
+
+func deleteBudget(budgetDAO *dao.BudgetDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+			return
+		}
+		if err := budgetDAO.DeleteBudget(middleware.UserID(c), id); err != nil {
+			respondWithBudgetError(c, err, "Failed to delete budget")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+	}
+}
+
+// getBudgetReport liefert geplante, ausgegebene und verbleibende Beträge je Budget
+// für einen Monat (YYYY-MM)
+func getBudgetReport(budgetDAO *dao.BudgetDAO, categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		month, err := time.Parse("2006-01", c.Param("month"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month, expected YYYY-MM"})
+			return
+		}
+		lines, err := budgetDAO.Report(middleware.Principal(c), month)
+		if err != nil {
+			respondWithBudgetError(c, err, "Failed to compute budget report")
+			return
+		}
+		categories, err := categoryDAO.ListCategories(middleware.UserID(c))
+		if err != nil {
+			respondWithBudgetError(c, err, "Failed to fetch categories")
+			return
+		}
+		c.JSON(http.StatusOK, toBudgetReportResponse(month, lines, toCategoryResponses(categories)))
+	}
+}
+
+func toBudgetResponse(b *models.Budget) BudgetResponse {
+	return BudgetResponse{
+		ID:         b.ID,
+		CategoryID: b.CategoryID,
+		Amount:     b.Amount,
+		Rollover:   b.Rollover,
+		StartMonth: b.StartMonth.Format("2006-01"),
+	}
+}
+
+func toBudgetReportResponse(month time.Time, lines []budget.Line, categories []CategoryResponse) BudgetReportResponse {
+	paths := make(map[int][]string, len(categories))
+	for _, category := range categories {
+		paths[category.ID] = category.Path
+	}
+	report := BudgetReportResponse{Month: month.Format("2006-01"), Lines: make([]BudgetLineResponse, 0, len(lines))}
+	for _, line := range lines {
+		report.Lines = append(report.Lines, BudgetLineResponse{
+			BudgetID:     line.BudgetID,
+			CategoryID:   line.CategoryID,
+			CategoryPath: paths[line.CategoryID],
+			Limit:        line.Limit,
+			Carryover:    line.Carryover,
+			Planned:      line.Planned,
+			Spent:        line.Spent,
+			Remaining:    line.Remaining,
+			Status:       line.Status,
+		})
+		report.Planned += line.Planned
+		report.Spent += line.Spent
+		report.Remaining += line.Remaining
+	}
+	report.Planned = budget.Round(report.Planned)
+	report.Spent = budget.Round(report.Spent)
+	report.Remaining = budget.Round(report.Remaining)
+	return report
+}
@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CategoryRequest ist der Body für das Anlegen und Ändern einer Kategorie.
+// Ohne parentid liegt die Kategorie auf oberster Ebene.
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parentid"`
+}
+
+// TagRequest ist der Body für das Anlegen und Umbenennen eines Tags
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+// CategoryResponse ist die API-Darstellung einer Kategorie; Path enthält die
+// Namen von der Wurzel bis zur Kategorie selbst, z.B. ["Wohnen", "Miete"]
+type CategoryResponse struct {
+	ID       int      `json:"ID"`
+	Name     string   `json:"Name"`
+	ParentID *int     `json:"ParentID"`
+	Path     []string `json:"Path"`
+}
+
+// TagResponse ist die API-Darstellung eines Tags
+type TagResponse struct {
+	ID   int    `json:"ID"`
+	Name string `json:"Name"`
+}
+
+// RegisterCategoryRoutes registriert die Verwaltung der eigenen Kategorien und Tags.
+// Zugeordnet werden sie über categories/tags der Ausgaben.
+func RegisterCategoryRoutes(r *gin.Engine, categoryDAO *dao.CategoryDAO, requireAuth, requireVerified gin.HandlerFunc) {
+	canRead := middleware.RequireScope(auth.ScopeExpensesRead)
+	canWrite := middleware.RequireScope(auth.ScopeExpensesWrite)
+
+	categoryRoutes := r.Group("/categories", requireAuth, requireVerified)
+	{
+		categoryRoutes.GET("/", canRead, listCategories(categoryDAO))
+		categoryRoutes.POST("/", canWrite, createCategory(categoryDAO))
+		categoryRoutes.PUT("/:id", canWrite, updateCategory(categoryDAO))
+		categoryRoutes.DELETE("/:id", canWrite, deleteCategory(categoryDAO))
+	}
+
+	tagRoutes := r.Group("/tags", requireAuth, requireVerified)
+	{
+		tagRoutes.GET("/", canRead, listTags(categoryDAO))
+		tagRoutes.POST("/", canWrite, createTag(categoryDAO))
+		tagRoutes.PUT("/:id", canWrite, renameTag(categoryDAO))
+		tagRoutes.DELETE("/:id", canWrite, deleteTag(categoryDAO))
+	}
+}
+
+// respondWithCategoryError übersetzt DAO-Fehler in HTTP-Statuscodes
+func respondWithCategoryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, dao.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, dao.ErrCategoryExists), errors.Is(err, dao.ErrTagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+func listCategories(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := categoryDAO.ListCategories(middleware.UserID(c))
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to fetch categories")
+			return
+		}
+		c.JSON(http.StatusOK, toCategoryResponses(categories))
+	}
+}
+
+func createCategory(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input CategoryRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		userID := middleware.UserID(c)
+		category, err := categoryDAO.CreateCategory(userID, input.Name, input.ParentID)
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to create category")
+			return
+		}
+		respondWithCategory(c, categoryDAO, userID, category, http.StatusCreated)
+	}
+}
+
+func updateCategory(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+		var input CategoryRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		userID := middleware.UserID(c)
+		category, err := categoryDAO.UpdateCategory(userID, id, input.Name, input.ParentID)
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to update category")
+			return
+		}
+		respondWithCategory(c, categoryDAO, userID, category, http.StatusOK)
+	}
+}
+
+func deleteCategory(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+		if err := categoryDAO.DeleteCategory(middleware.UserID(c), id); err != nil {
+			respondWithCategoryError(c, err, "Failed to delete category")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	}
+}
+
+// respondWithCategory liefert eine einzelne Kategorie samt Pfad
+func respondWithCategory(c *gin.Context, categoryDAO *dao.CategoryDAO, userID int, category *models.Category, status int) {
+	categories, err := categoryDAO.ListCategories(userID)
+	if err != nil {
+		respondWithCategoryError(c, err, "Failed to fetch categories")
+		return
+	}
+	for _, response := range toCategoryResponses(categories) {
+		if response.ID == category.ID {
+			c.JSON(status, response)
+			return
+		}
+	}
+	c.JSON(status, CategoryResponse{ID: category.ID, Name: category.Name, ParentID: category.ParentID, Path: []string{category.Name}})
+}
+
+// toCategoryResponses ergänzt jede Kategorie um ihren Pfad im Baum
+func toCategoryResponses(categories []models.Category) []CategoryResponse {
+	byID := make(map[int]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	result := make([]CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		path := []string{category.Name}
+		// Tiefe begrenzt, falls der Baum in der DB doch einmal einen Zyklus hat
+		for parentID := category.ParentID; parentID != nil && len(path) <= len(categories); {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			path = append([]string{parent.Name}, path...)
+			parentID = parent.ParentID
+		}
+		result = append(result, CategoryResponse{ID: category.ID, Name: category.Name, ParentID: category.ParentID, Path: path})
+	}
+	return result
+}
+
+func listTags(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tags, err := categoryDAO.ListTags(middleware.UserID(c))
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to fetch tags")
+			return
+		}
+		result := make([]TagResponse, 0, len(tags))
+		for _, tag := range tags {
+			result = append(result, TagResponse{ID: tag.ID, Name: tag.Name})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func createTag(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TagRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		tag, err := categoryDAO.CreateTag(middleware.UserID(c), input.Name)
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to create tag")
+			return
+		}
+		c.JSON(http.StatusCreated, TagResponse{ID: tag.ID, Name: tag.Name})
+	}
+}
+
+func renameTag(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+			return
+		}
+		var input TagRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		tag, err := categoryDAO.RenameTag(middleware.UserID(c), id, input.Name)
+		if err != nil {
+			respondWithCategoryError(c, err, "Failed to rename tag")
+			return
+		}
+		c.JSON(http.StatusOK, TagResponse{ID: tag.ID, Name: tag.Name})
+	}
+}
+
+func deleteTag(categoryDAO *dao.CategoryDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+			return
+		}
+		if err := categoryDAO.DeleteTag(middleware.UserID(c), id); err != nil {
+			respondWithCategoryError(c, err, "Failed to delete tag")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+	}
+}
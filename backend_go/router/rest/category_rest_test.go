@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"backend_go/db/models"
+	"reflect"
+	"testing"
+)
+
+func TestToCategoryResponsesBuildsPaths(t *testing.T) {
+	living, rent := 1, 2
+	categories := []models.Category{
+		{ID: 3, Name: "Kaltmiete", ParentID: &rent},
+		{ID: 2, Name: "Miete", ParentID: &living},
+		{ID: 1, Name: "Wohnen"},
+	}
+	responses := toCategoryResponses(categories)
+	want := map[int][]string{
+		1: {"Wohnen"},
+		2: {"Wohnen", "Miete"},
+		3: {"Wohnen", "Miete", "Kaltmiete"},
+	}
+	for _, r := range responses {
+		if !reflect.DeepEqual(r.Path, want[r.ID]) {
+			t.Errorf("category %d: path %v, want %v", r.ID, r.Path, want[r.ID])
+		}
+	}
+}
+
+func TestToCategoryResponsesStopsOnCycles(t *testing.T) {
+	a, b := 1, 2
+	categories := []models.Category{{ID: 1, Name: "A", ParentID: &b}, {ID: 2, Name: "B", ParentID: &a}}
+	for _, r := range toCategoryResponses(categories) {
+		if len(r.Path) > 3 {
+			t.Fatalf("path of a cycle was not cut off: %v", r.Path)
+		}
+	}
+}
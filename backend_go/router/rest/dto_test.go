@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToUserResponseOmitsPassword(t *testing.T) {
+	verifiedAt := time.Now()
+	username := "tobi"
+	user := &models.User{
+		ID:             7,
+		Name:           "Tobi",
+		Username:       &username,
+		Email:          "tobi@example.com",
+		Password:       "$2a$12$secrethash",
+		Income:         3200,
+		Accountbalance: 150.5,
+		VerifiedAt:     &verifiedAt,
+	}
+
+	resp := toUserResponse(user)
+	if resp.ID != 7 || resp.Name != "Tobi" || resp.Username != "tobi" || resp.Email != "tobi@example.com" || resp.Income != 3200 || resp.Accountbalance != 150.5 || !resp.Verified {
+		t.Fatalf("unexpected mapping: %+v", resp)
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "password") || strings.Contains(string(body), "secrethash") {
+		t.Fatalf("response leaks the password: %s", body)
+	}
+}
+
+func TestUserModelNeverMarshalsPassword(t *testing.T) {
+	body, err := json.Marshal(models.User{ID: 1, Password: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "plain") {
+		t.Fatalf("model leaks the password: %s", body)
+	}
+}
+
+func TestToUserResponses(t *testing.T) {
+	users := []models.User{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	resp := toUserResponses(users)
+	if len(resp) != 2 || resp[0].ID != 1 || resp[1].Name != "b" {
+		t.Fatalf("unexpected mapping: %+v", resp)
+	}
+	if got := toUserResponses(nil); got == nil || len(got) != 0 {
+		t.Fatalf("expected empty non-nil slice, got %#v", got)
+	}
+}
+
+func TestCreateUserRequestIgnoresMassAssignment(t *testing.T) {
+	var req CreateUserRequest
+	body := `{"id": 99, "name": "n", "email": "e@example.com", "password": "longenough", "accountbalance": 1000000}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req != (CreateUserRequest{Name: "n", Email: "e@example.com", Password: "longenough"}) {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestToExpenseResponse(t *testing.T) {
+	due := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expense := &models.Haushaltsausgaben{
+		ID:              3,
+		Description:     "Miete",
+		ValueTotal:      950,
+		Type:            "monthlycosts",
+		UserID:          7,
+		Faelligkeitstag: "1",
+		Zahldatum:       due,
+		Receipt:         []byte("%PDF-1.7"),
+	}
+
+	resp := toExpenseResponse(expense)
+	if resp.ID != 3 || resp.Description != "Miete" || resp.ValueTotal != 950 || resp.Type != "monthlycosts" ||
+		resp.UserID != 7 || resp.Faelligkeitstag != "1" || !resp.Zahldatum.Equal(due) || !resp.HasReceipt {
+		t.Fatalf("unexpected mapping: %+v", resp)
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"Receipt"`) || strings.Contains(string(body), "JVBERi") {
+		t.Fatalf("response contains receipt bytes: %s", body)
+	}
+	// The frontend reads the Go field names, keep them stable
+	for _, key := range []string{`"ID"`, `"ValueTotal"`, `"CreditStart"`, `"Zahldatum"`, `"Faelligkeitstag"`} {
+		if !strings.Contains(string(body), key) {
+			t.Errorf("missing key %s in %s", key, body)
+		}
+	}
+}
+
+func TestToExpenseOccurrenceResponsesKeepsExpenseKeys(t *testing.T) {
+	due := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	occurrences := []dao.ExpenseOccurrence{{
+		Expense: models.Haushaltsausgaben{ID: 4, Description: "Versicherung", Recurrence: "quarterly", RecurrenceInterval: 3},
+		DueDate: due,
+	}}
+
+	resp := toExpenseOccurrenceResponses(occurrences)
+	if len(resp) != 1 || resp[0].ID != 4 || !resp[0].DueDate.Equal(due) || resp[0].Recurrence.Frequency != "quarterly" {
+		t.Fatalf("unexpected mapping: %+v", resp)
+	}
+	body, err := json.Marshal(resp[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Expense fields stay at the top level, like the raw rows before
+	for _, key := range []string{`"ID":4`, `"Description"`, `"DueDate"`, `"Recurrence":{"Frequency":"quarterly"`} {
+		if !strings.Contains(string(body), key) {
+			t.Errorf("missing %s in %s", key, body)
+		}
+	}
+}
+
+func TestExpenseRequestIgnoresOwnerAndID(t *testing.T) {
+	var req ExpenseRequest
+	body := `{"id": 5, "userid": 42, "receipt": "AAAA", "description": "Tanken", "valuetotal": 60.5, "type": "allelse"}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Description != "Tanken" || req.ValueTotal != 60.5 || req.Type != "allelse" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/einvoice"
+	"backend_go/receipt"
+	"backend_go/router/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expenseCurrency ist die Währung aller Beträge von Ausgaben
+const expenseCurrency = "EUR"
+
+// EInvoiceResponse enthält die aus der E-Rechnung gelesenen Daten
+type EInvoiceResponse struct {
+	Format    string     `json:"Format"` // CII oder UBL
+	Number    string     `json:"Number"`
+	IssueDate time.Time  `json:"IssueDate"`
+	DueDate   *time.Time `json:"DueDate"`
+	Seller    string     `json:"Seller"`
+	Currency  string     `json:"Currency"`
+	Total     float64    `json:"Total"`
+}
+
+// EInvoiceImportResponse ist die angelegte Ausgabe samt den erkannten Rechnungsdaten
+type EInvoiceImportResponse struct {
+	Expense ExpenseResponse  `json:"Expense"`
+	Invoice EInvoiceResponse `json:"Invoice"`
+}
+
+// importEInvoice legt aus einer XRechnung (XML) oder einem ZUGFeRD/Factur-X-PDF
+// (Feld "file") eine Ausgabe vom Typ invoice an. Betrag ist der Zahlbetrag,
+// zahldatum das Fälligkeitsdatum (ersatzweise das Rechnungsdatum), die
+// Beschreibung der Rechnungssteller. Die Datei wird als Beleg gespeichert; die
+// Ausgabe kann danach wie jede andere geprüft und angepasst werden.
+//
+// Einen Entwurfsstatus gibt es bewusst nicht: die Ausgabe zählt sofort in
+// Monatsansicht, Fälligkeiten und Auswertungen mit, so wie eine von Hand
+// angelegte. Wer die Rechnung nicht übernehmen will, löscht die Ausgabe wieder.
+// Beträge werden nicht umgerechnet, deshalb werden nur Rechnungen in Euro
+// angenommen, alle anderen mit 422 abgelehnt.
+func importEInvoice(expenseDAO *dao.HaushaltsausgabenDAO, maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, filename, ok := readUploadedFile(c, maxSize)
+		if !ok {
+			return
+		}
+
+		invoice, err := einvoice.Parse(data)
+		if err != nil {
+			log.Printf("[Handler.importEInvoice] Rejected e-invoice: %v", err)
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, einvoice.ErrUnsupportedFormat) {
+				status = http.StatusUnsupportedMediaType
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !strings.EqualFold(invoice.Currency, expenseCurrency) {
+			log.Printf("[Handler.importEInvoice] Rejected e-invoice in currency %q", invoice.Currency)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("Only invoices in %s can be imported, not %q", expenseCurrency, invoice.Currency)})
+			return
+		}
+
+		zahldatum := invoice.IssueDate
+		if invoice.DueDate != nil {
+			zahldatum = *invoice.DueDate
+		}
+		principal := middleware.Principal(c)
+		created, err := expenseDAO.Create(
+			principal,
+			invoice.Seller,
+			invoice.Total,
+			0,
+			time.Time{},
+			time.Time{},
+			"invoice",
+			strconv.Itoa(zahldatum.Day()),
+			zahldatum,
+			nil, // einmalig zum Zahldatum, siehe models.LegacyRecurrence
+			dao.ExpenseLabels{},
+		)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to create expense")
+			return
+		}
+
+		contentType := receipt.TypeXML
+		if detected, err := receipt.DetectType(data); err == nil {
+			contentType = detected
+		}
+		expense, err := expenseDAO.SetReceipt(principal, created.ID, data, contentType, receiptFilename(filename, contentType), nil)
+		if err != nil {
+			// Ohne Beleg ist der Import unvollständig, die Ausgabe wird wieder entfernt
+			if delErr := expenseDAO.Delete(principal, created.ID); delErr != nil {
+				log.Printf("[Handler.importEInvoice] ERROR removing incomplete import: %v", delErr)
+			}
+			respondWithExpenseError(c, err, "Failed to store receipt")
+			return
+		}
+
+		log.Printf("[Handler.importEInvoice] Imported %s invoice %q from %q as expense ID %d", invoice.Format, invoice.Number, invoice.Seller, expense.ID)
+		c.Header("ETag", expenseETag(expense))
+		c.JSON(http.StatusCreated, EInvoiceImportResponse{
+			Expense: toExpenseResponse(expense),
+			Invoice: EInvoiceResponse{
+				Format:    invoice.Format,
+				Number:    invoice.Number,
+				IssueDate: invoice.IssueDate,
+				DueDate:   invoice.DueDate,
+				Seller:    invoice.Seller,
+				Currency:  invoice.Currency,
+				Total:     invoice.Total,
+			},
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportEInvoiceRejectsForeignCurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fixture, err := os.ReadFile("../../einvoice/testdata/xrechnung-ubl.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice := bytes.ReplaceAll(fixture, []byte("EUR"), []byte("USD"))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "rechnung.xml")
+	part.Write(invoice)
+	form.Close()
+
+	r := gin.New()
+	// The DAO is nil: the invoice must be rejected before anything is stored
+	r.POST("/import", importEInvoice(nil, 1<<20))
+	req := httptest.NewRequest(http.MethodPost, "/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "USD") {
+		t.Fatalf("got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"time"
+)
+
+// ExpenseRequest ist der Body für das Anlegen und Ändern einer Ausgabe.
+// ID, Besitzer, Zeitstempel und Beleg können darüber nicht gesetzt werden.
+type ExpenseRequest struct {
+	Description     string    `json:"description"`
+	ValueTotal      float64   `json:"valuetotal"`
+	ValueRate       float64   `json:"valuerate"`
+	CreditStart     time.Time `json:"creditstart"`
+	CreditEnd       time.Time `json:"creditend"`
+	Type            string    `json:"type"`
+	Faelligkeitstag string    `json:"faelligkeitstag"`
+	Zahldatum       time.Time `json:"zahldatum"`
+	// Ohne Regel gilt beim Anlegen die bisherige Bedeutung des Typs (siehe
+	// models.LegacyRecurrence). Beim Ändern bleibt eine gesetzte Regel bestehen; folgt
+	// sie noch dem Typ, wird sie aus den neuen Werten abgeleitet.
+	Recurrence *RecurrenceRequest `json:"recurrence"`
+	// Kategorie-IDs und Tag-Namen; fehlt das Feld, bleibt die Zuordnung unverändert,
+	// [] entfernt sie. Unbekannte Tags werden angelegt.
+	Categories []int    `json:"categories"`
+	Tags       []string `json:"tags"`
+}
+
+// labels liefert die Zuordnungen der Anfrage für das DAO
+func (r *ExpenseRequest) labels() dao.ExpenseLabels {
+	return dao.ExpenseLabels{CategoryIDs: r.Categories, Tags: r.Tags}
+}
+
+// RecurrenceRequest beschreibt, wann eine Ausgabe fällig wird. Der Tag im Monat
+// kommt aus faelligkeitstag, sonst aus start.
+type RecurrenceRequest struct {
+	Frequency string     `json:"frequency"` // once, monthly, quarterly, yearly, every_n_months
+	Interval  int        `json:"interval"`  // Monate, nur für every_n_months
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end"`
+}
+
+// rule wandelt die Anfrage in eine Regel um; nil, wenn keine Regel mitgeschickt wurde
+func (r *RecurrenceRequest) rule() *recurrence.Rule {
+	if r == nil {
+		return nil
+	}
+	return &recurrence.Rule{
+		Frequency: recurrence.Frequency(r.Frequency),
+		Interval:  r.Interval,
+		Start:     r.Start,
+		End:       r.End,
+	}
+}
+
+// ExpenseResponse ist die API-Darstellung einer Ausgabe. Die Feldnamen entsprechen
+// dem bisherigen Format, das das Frontend erwartet; der Beleg selbst wird nie mitgeliefert.
+type ExpenseResponse struct {
+	ID              int                `json:"ID"`
+	Description     string             `json:"Description"`
+	ValueTotal      float64            `json:"ValueTotal"`
+	ValueRate       float64            `json:"ValueRate"`
+	CreditStart     time.Time          `json:"CreditStart"`
+	CreditEnd       time.Time          `json:"CreditEnd"`
+	Type            string             `json:"Type"`
+	UserID          int                `json:"UserID"`
+	CreatedAt       time.Time          `json:"CreatedAt"`
+	ChangedAt       time.Time          `json:"ChangedAt"`
+	Faelligkeitstag string             `json:"Faelligkeitstag"`
+	Zahldatum       time.Time          `json:"Zahldatum"`
+	HasReceipt      bool               `json:"HasReceipt"`
+	Recurrence      RecurrenceResponse `json:"Recurrence"`
+	Categories      []CategoryRef      `json:"Categories"`
+	Tags            []string           `json:"Tags"`
+	// Version für If-Match bei PATCH, ändert sich mit jeder Änderung
+	Version string `json:"Version"`
+}
+
+// CategoryRef ist eine zugeordnete Kategorie; den Baum liefert GET /categories
+type CategoryRef struct {
+	ID       int    `json:"ID"`
+	Name     string `json:"Name"`
+	ParentID *int   `json:"ParentID"`
+}
+
+// RecurrenceResponse ist die API-Darstellung der Wiederholungsregel
+type RecurrenceResponse struct {
+	Frequency string     `json:"Frequency"`
+	Interval  int        `json:"Interval"`
+	Start     time.Time  `json:"Start"`
+	End       *time.Time `json:"End"`
+}
+
+// ExpenseOccurrenceResponse ist eine konkrete Fälligkeit: die Ausgabe plus Fälligkeitsdatum
+type ExpenseOccurrenceResponse struct {
+	ExpenseResponse
+	DueDate time.Time `json:"DueDate"`
+}
+
+// toExpenseResponse wandelt das DB-Modell in die API-Darstellung um
+func toExpenseResponse(expense *models.Haushaltsausgaben) ExpenseResponse {
+	return ExpenseResponse{
+		ID:              expense.ID,
+		Description:     expense.Description,
+		ValueTotal:      expense.ValueTotal,
+		ValueRate:       expense.ValueRate,
+		CreditStart:     expense.CreditStart,
+		CreditEnd:       expense.CreditEnd,
+		Type:            expense.Type,
+		UserID:          expense.UserID,
+		CreatedAt:       expense.CreatedAt,
+		ChangedAt:       expense.ChangedAt,
+		Faelligkeitstag: expense.Faelligkeitstag,
+		Zahldatum:       expense.Zahldatum,
+		HasReceipt:      expense.HasReceipt(),
+		Recurrence: RecurrenceResponse{
+			Frequency: expense.Recurrence,
+			Interval:  expense.RecurrenceInterval,
+			Start:     expense.RecurrenceStart,
+			End:       expense.RecurrenceEnd,
+		},
+		Categories: toCategoryRefs(expense.Categories),
+		Tags:       tagNames(expense.Tags),
+		Version:    expense.Version(),
+	}
+}
+
+func toCategoryRefs(categories []models.Category) []CategoryRef {
+	refs := make([]CategoryRef, 0, len(categories))
+	for _, c := range categories {
+		refs = append(refs, CategoryRef{ID: c.ID, Name: c.Name, ParentID: c.ParentID})
+	}
+	return refs
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// toExpenseResponses wandelt eine Liste von DB-Modellen um
+func toExpenseResponses(expenses []models.Haushaltsausgaben) []ExpenseResponse {
+	result := make([]ExpenseResponse, 0, len(expenses))
+	for i := range expenses {
+		result = append(result, toExpenseResponse(&expenses[i]))
+	}
+	return result
+}
+
+// toExpenseOccurrenceResponses wandelt Fälligkeiten in die API-Darstellung um
+func toExpenseOccurrenceResponses(occurrences []dao.ExpenseOccurrence) []ExpenseOccurrenceResponse {
+	result := make([]ExpenseOccurrenceResponse, 0, len(occurrences))
+	for i := range occurrences {
+		result = append(result, ExpenseOccurrenceResponse{
+			ExpenseResponse: toExpenseResponse(&occurrences[i].Expense),
+			DueDate:         occurrences[i].DueDate,
+		})
+	}
+	return result
+}
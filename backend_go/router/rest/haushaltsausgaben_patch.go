@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// mergePatchContentType ist der Medientyp von JSON Merge Patch (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// expenseETag liefert den ETag-Wert zur Version eines Eintrags
+func expenseETag(expense *models.Haushaltsausgaben) string {
+	return `"` + expense.Version() + `"`
+}
+
+// parseIfMatch liest die erwartete Version aus If-Match. Schwache ETags und
+// Listen werden nicht unterstützt; "*" würde die Prüfung aushebeln.
+func parseIfMatch(header string) (string, bool) {
+	header = strings.TrimSpace(header)
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return "", false
+	}
+	return header[1 : len(header)-1], true
+}
+
+// patchError ist ein Eingabefehler im Patch-Dokument
+type patchError struct {
+	Field   string
+	Message string
+}
+
+func (e *patchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// patchableExpenseFields sind die Felder, die ein Patch ändern darf, in der
+// Reihenfolge der Anwendung: recurrence zuletzt, weil null auf den Typ zurückgreift
+var patchableExpenseFields = []string{
+	"description", "valuetotal", "valuerate", "creditstart", "creditend",
+	"type", "faelligkeitstag", "zahldatum", "recurrence", "categories", "tags",
+}
+
+// applyExpensePatch wendet ein Merge-Patch-Dokument auf den Eintrag an. Nur
+// enthaltene Felder werden geändert, null setzt ein Feld zurück. Die Feldnamen
+// entsprechen ExpenseRequest.
+func applyExpensePatch(expense *models.Haushaltsausgaben, body []byte) error {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return &patchError{Field: "body", Message: "must be a JSON object"}
+	}
+	for field := range patch {
+		switch {
+		case field == "id" || field == "userid" || field == "receipt":
+			return &patchError{Field: field, Message: "cannot be changed"}
+		case !slices.Contains(patchableExpenseFields, field):
+			return &patchError{Field: field, Message: "unknown field"}
+		}
+	}
+
+	for _, field := range patchableExpenseFields {
+		raw, ok := patch[field]
+		if !ok {
+			continue
+		}
+		var err error
+		switch field {
+		case "description":
+			err = decodePatchValue(raw, &expense.Description)
+		case "valuetotal":
+			err = decodePatchValue(raw, &expense.ValueTotal)
+			if err == nil && expense.ValueTotal <= 0 {
+				return &patchError{Field: field, Message: "must be positive"}
+			}
+		case "valuerate":
+			err = decodePatchValue(raw, &expense.ValueRate)
+		case "creditstart":
+			err = decodePatchValue(raw, &expense.CreditStart)
+		case "creditend":
+			err = decodePatchValue(raw, &expense.CreditEnd)
+		case "type":
+			err = decodePatchValue(raw, &expense.Type)
+			if err == nil && expense.Type == "" {
+				return &patchError{Field: field, Message: "must not be empty"}
+			}
+		case "faelligkeitstag":
+			err = decodePatchValue(raw, &expense.Faelligkeitstag)
+		case "zahldatum":
+			err = decodePatchValue(raw, &expense.Zahldatum)
+		case "recurrence":
+			err = applyRecurrencePatch(expense, raw)
+		case "categories":
+			// Listen ersetzen die Zuordnung vollständig (RFC 7396), null entfernt sie
+			var ids []int
+			err = decodePatchValue(raw, &ids)
+			expense.Categories = make([]models.Category, 0, len(ids))
+			for _, id := range ids {
+				expense.Categories = append(expense.Categories, models.Category{ID: id})
+			}
+		case "tags":
+			var names []string
+			err = decodePatchValue(raw, &names)
+			expense.Tags = make([]models.Tag, 0, len(names))
+			for _, name := range names {
+				expense.Tags = append(expense.Tags, models.Tag{Name: name})
+			}
+		}
+		if err != nil {
+			return &patchError{Field: field, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// applyRecurrencePatch führt das Teilobjekt "recurrence" zusammen. null setzt die
+// Regel auf die Bedeutung des Typs zurück (siehe models.LegacyRecurrence).
+func applyRecurrencePatch(expense *models.Haushaltsausgaben, raw json.RawMessage) error {
+	if isJSONNull(raw) {
+		expense.SetRecurrence(models.LegacyRecurrence(expense.Type, expense.CreatedAt, expense.CreditStart, expense.CreditEnd, expense.Zahldatum))
+		return nil
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &patch); err != nil || patch == nil {
+		return fmt.Errorf("must be an object or null")
+	}
+	rule := expense.RecurrenceRule()
+	for field, value := range patch {
+		var err error
+		switch field {
+		case "frequency":
+			var frequency string
+			err = decodePatchValue(value, &frequency)
+			rule.Frequency = recurrence.Frequency(frequency)
+		case "interval":
+			err = decodePatchValue(value, &rule.Interval)
+		case "start":
+			err = decodePatchValue(value, &rule.Start)
+		case "end":
+			var end *time.Time
+			err = decodePatchValue(value, &end)
+			rule.End = end
+		default:
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+	}
+	expense.SetRecurrence(rule)
+	return nil
+}
+
+// decodePatchValue setzt target (ein Zeiger) auf den Wert aus raw; null ergibt den
+// Nullwert, json.Unmarshal allein würde das Feld bei null unverändert lassen
+func decodePatchValue(raw json.RawMessage, target interface{}) error {
+	if isJSONNull(raw) {
+		v := reflect.ValueOf(target).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("invalid value")
+	}
+	return nil
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"errors"
+	"testing"
+	"time"
+)
+
+func patchFixture() models.Haushaltsausgaben {
+	end := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	return models.Haushaltsausgaben{
+		ID:                 9,
+		UserID:             3,
+		Description:        "Autokredit",
+		ValueTotal:         12000,
+		ValueRate:          250,
+		Type:               "credit",
+		Faelligkeitstag:    "15",
+		Recurrence:         "monthly",
+		RecurrenceInterval: 1,
+		RecurrenceStart:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		RecurrenceEnd:      &end,
+	}
+}
+
+func TestApplyExpensePatchChangesOnlySuppliedFields(t *testing.T) {
+	expense := patchFixture()
+	if err := applyExpensePatch(&expense, []byte(`{"valuerate": 275.5}`)); err != nil {
+		t.Fatal(err)
+	}
+	want := patchFixture()
+	want.ValueRate = 275.5
+	if expense.ValueRate != 275.5 || expense.Description != want.Description || expense.ValueTotal != want.ValueTotal ||
+		expense.Faelligkeitstag != want.Faelligkeitstag || expense.RecurrenceEnd == nil {
+		t.Fatalf("unexpected result: %+v", expense)
+	}
+}
+
+func TestApplyExpensePatchNullResetsField(t *testing.T) {
+	expense := patchFixture()
+	if err := applyExpensePatch(&expense, []byte(`{"faelligkeitstag": null, "description": ""}`)); err != nil {
+		t.Fatal(err)
+	}
+	if expense.Faelligkeitstag != "" || expense.Description != "" {
+		t.Fatalf("fields not reset: %+v", expense)
+	}
+}
+
+func TestApplyExpensePatchMergesRecurrence(t *testing.T) {
+	expense := patchFixture()
+	body := `{"recurrence": {"frequency": "every_n_months", "interval": 2, "end": null}}`
+	if err := applyExpensePatch(&expense, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if expense.Recurrence != string(recurrence.EveryNMonths) || expense.RecurrenceInterval != 2 ||
+		!expense.RecurrenceStart.Equal(patchFixture().RecurrenceStart) || expense.RecurrenceEnd != nil {
+		t.Fatalf("unexpected recurrence: %+v", expense)
+	}
+}
+
+func TestApplyExpensePatchRejectsInvalidDocuments(t *testing.T) {
+	for _, body := range []string{
+		`[]`,
+		`null`,
+		`{"userid": 4}`,
+		`{"receipt": "AAAA"}`,
+		`{"valuetotal": 0}`,
+		`{"valuetotal": "viel"}`,
+		`{"type": null}`,
+		`{"unknown": 1}`,
+		`{"recurrence": {"weekday": 1}}`,
+	} {
+		expense := patchFixture()
+		err := applyExpensePatch(&expense, []byte(body))
+		var pe *patchError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: expected a patch error, got %v", body, err)
+		}
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := map[string]struct {
+		version string
+		ok      bool
+	}{
+		`"1714554000123456"`:   {"1714554000123456", true},
+		` "0" `:                {"0", true},
+		`1714554000123456`:     {"", false},
+		`W/"1714554000123456"`: {"", false},
+		`*`:                    {"", false},
+		`""`:                   {"", false},
+	}
+	for header, want := range tests {
+		version, ok := parseIfMatch(header)
+		if version != want.version || ok != want.ok {
+			t.Errorf("parseIfMatch(%q) = %q, %v", header, version, ok)
+		}
+	}
+}
+
+func TestExpenseVersionMatchesETag(t *testing.T) {
+	expense := models.Haushaltsausgaben{ChangedAt: time.Date(2025, 5, 1, 12, 0, 0, 123456789, time.UTC)}
+	version, ok := parseIfMatch(expenseETag(&expense))
+	if !ok || version != expense.Version() {
+		t.Fatalf("ETag %s does not round-trip to version %s", expenseETag(&expense), expense.Version())
+	}
+	if (&models.Haushaltsausgaben{}).Version() != "0" {
+		t.Fatal("expected version 0 for rows without changed_at")
+	}
+}
+
+func TestApplyExpensePatchReplacesLabels(t *testing.T) {
+	expense := patchFixture()
+	expense.Categories = []models.Category{{ID: 1, Name: "Mobilität"}}
+	expense.Tags = []models.Tag{{ID: 4, Name: "auto"}}
+
+	if err := applyExpensePatch(&expense, []byte(`{"categories": [2, 3]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(expense.Categories) != 2 || expense.Categories[0].ID != 2 || expense.Categories[1].ID != 3 {
+		t.Fatalf("categories not replaced: %+v", expense.Categories)
+	}
+	if len(expense.Tags) != 1 {
+		t.Fatalf("tags changed although not in the patch: %+v", expense.Tags)
+	}
+
+	if err := applyExpensePatch(&expense, []byte(`{"tags": null}`)); err != nil {
+		t.Fatal(err)
+	}
+	if expense.Tags == nil || len(expense.Tags) != 0 {
+		t.Fatalf("null must clear the tags: %+v", expense.Tags)
+	}
+}
@@ -1,58 +1,102 @@
 package rest
 
 import (
+	"backend_go/auth"
 	"backend_go/db/dao"
 	"backend_go/db/models"
+	"backend_go/recurrence"
+	"backend_go/router/middleware"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func RegisterHaushaltsausgabenRoutes(r *gin.Engine, expenseDAO *dao.HaushaltsausgabenDAO) {
-	expenseRoutes := r.Group("/haushaltsausgaben")
+func RegisterHaushaltsausgabenRoutes(r *gin.Engine, expenseDAO *dao.HaushaltsausgabenDAO, requireAuth, requireVerified gin.HandlerFunc, maxReceiptSize int64) {
+	// API-Tokens brauchen den passenden Scope, Login-Sessions dürfen alles
+	canRead := middleware.RequireScope(auth.ScopeExpensesRead)
+	canWrite := middleware.RequireScope(auth.ScopeExpensesWrite)
+
+	expenseRoutes := r.Group("/haushaltsausgaben", requireAuth, requireVerified)
 	{
-		expenseRoutes.POST("/", createExpense(expenseDAO))
-		expenseRoutes.GET("/", getExpenses(expenseDAO))
-		expenseRoutes.PUT("/:id", updateExpense(expenseDAO))
-		expenseRoutes.DELETE("/:id", deleteExpense(expenseDAO))
-		expenseRoutes.GET("/:userid/:month", getExpensesByUserAndMonth(expenseDAO))
+		expenseRoutes.POST("/", canWrite, createExpense(expenseDAO))
+		expenseRoutes.GET("/", canRead, getExpenses(expenseDAO))
+		expenseRoutes.PUT("/:id", canWrite, updateExpense(expenseDAO))
+		expenseRoutes.PATCH("/:id", canWrite, patchExpense(expenseDAO))
+		expenseRoutes.DELETE("/:id", canWrite, deleteExpense(expenseDAO))
+		expenseRoutes.POST("/import/einvoice", canWrite, importEInvoice(expenseDAO, maxReceiptSize))
+		expenseRoutes.POST("/:id/receipt", canWrite, uploadReceipt(expenseDAO, maxReceiptSize))
+		expenseRoutes.DELETE("/:id/receipt", canWrite, deleteReceipt(expenseDAO))
+		expenseRoutes.GET("/:id/receipt", canRead, downloadReceipt(expenseDAO))
+		expenseRoutes.GET("/:id/receipt/thumbnail", canRead, getReceiptThumbnail(expenseDAO))
+		// Unter eigenem Präfix, gin verlangt an derselben Stelle denselben Platzhalternamen wie /:id
+		expenseRoutes.GET("/user/:userid/occurrences", canRead, getExpenseOccurrences(expenseDAO))
+		expenseRoutes.GET("/user/:userid/:month", canRead, getExpensesByUserAndMonth(expenseDAO))
+	}
+}
+
+// respondWithExpenseError übersetzt DAO-Fehler in HTTP-Statuscodes
+func respondWithExpenseError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to access this expense"})
+	case errors.Is(err, dao.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Expense was changed in the meantime, reload it and try again"})
+	case errors.Is(err, dao.ErrInvalidRecurrence), errors.Is(err, dao.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
 	}
 }
 
 func getExpenses(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userIDStr := c.DefaultQuery("user_id", "")
-		var userID int
-		var err error
-		if userIDStr != "" {
-			userID, err = strconv.Atoi(userIDStr)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		principal := middleware.Principal(c)
+
+		// Filter: ?category=<id> (mit Unterkategorien, mehrfach = ODER), ?tag=<name> (mehrfach = UND)
+		filter := dao.ExpenseFilter{Tags: c.QueryArray("tag")}
+		for _, idStr := range c.QueryArray("category") {
+			id, convErr := strconv.Atoi(idStr)
+			if convErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
 				return
 			}
+			filter.CategoryIDs = append(filter.CategoryIDs, id)
 		}
 
+		// Ohne user_id werden alle für den Aufrufer sichtbaren Ausgaben geliefert
 		var expenses []models.Haushaltsausgaben
-		if userID > 0 {
-			expenses, err = expenseDAO.GetByUserID(userID)
+		var err error
+		if userIDStr := c.DefaultQuery("user_id", ""); userIDStr != "" {
+			userID, convErr := strconv.Atoi(userIDStr)
+			if convErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+				return
+			}
+			expenses, err = expenseDAO.GetByUserID(principal, userID, filter)
 		} else {
-			expenses, err = expenseDAO.GetAll()
+			expenses, err = expenseDAO.GetAll(principal, filter)
 		}
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+			respondWithExpenseError(c, err, "Failed to fetch expenses")
 			return
 		}
-		c.JSON(http.StatusOK, expenses)
+		c.JSON(http.StatusOK, toExpenseResponses(expenses))
 	}
 }
 
 func createExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. Definiere eine Variable für die Eingabedaten (kann das DB-Modell sein)
-		var input models.Haushaltsausgaben // Verwende dein GORM-Modell
+		// 1. Definiere eine Variable für die Eingabedaten (eigener Request-Typ, nicht das DB-Modell)
+		var input ExpenseRequest
 
 		// 2. Binde den JSON-Body an die 'input'-Struktur
 		log.Println("[Handler.createExpense] Attempting to bind JSON body...") // Logging hinzugefügt
@@ -64,8 +108,8 @@ func createExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 		}
 
 		// 3. JSON erfolgreich gebunden - Logge die empfangenen Daten (optional, auf sensible Daten achten)
-		log.Printf("[Handler.createExpense] Successfully bound JSON: UserID=%d, Type=%s, ValueTotal=%.2f, Description=%s, Faelligkeitstag=%s, CreditStart=%v, CreditEnd=%v",
-			input.UserID, input.Type, input.ValueTotal, input.Description, input.Faelligkeitstag, input.CreditStart, input.CreditEnd)
+		log.Printf("[Handler.createExpense] Successfully bound JSON: Type=%s, ValueTotal=%.2f, Description=%s, Faelligkeitstag=%s, CreditStart=%v, CreditEnd=%v",
+			input.Type, input.ValueTotal, input.Description, input.Faelligkeitstag, input.CreditStart, input.CreditEnd)
 
 		// 4. Validierung der gebundenen Daten (Beispiele)
 		if input.ValueTotal <= 0 {
@@ -73,13 +117,8 @@ func createExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valuetotal, must be positive"})
 			return
 		}
-		// Achte auf den Feldnamen: Das Frontend sendet 'user_id' oder 'userid'?
-		// Wenn 'userid' gesendet wird, füge `json:"userid"` zum UserID Feld im Model hinzu.
-		if input.UserID <= 0 {
-			log.Println("[Handler.createExpense] Validation failed: UserID is missing or invalid.")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing userid"})
-			return
-		}
+		// Der Besitzer kommt immer aus dem Access Token, eine mitgeschickte userid wird ignoriert
+		principal := middleware.Principal(c)
 		if input.Type == "" {
 			log.Println("[Handler.createExpense] Validation failed: Type is missing.")
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing expense type"})
@@ -91,24 +130,27 @@ func createExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 		//    Die Felder ValueRate und Zahldatum werden aus dem input übernommen (sind ggf. Nullwerte)
 		log.Println("[Handler.createExpense] Calling DAO.Create...")
 		expense, err := expenseDAO.Create(
+			principal,
 			input.Description,
 			input.ValueTotal,
 			input.ValueRate,   // Kommt aus dem gebundenen JSON (ggf. 0)
 			input.CreditStart, // Kommt aus dem gebundenen JSON (ggf. time.Time{})
 			input.CreditEnd,   // Kommt aus dem gebundenen JSON (ggf. time.Time{})
 			input.Type,
-			input.UserID,
 			input.Faelligkeitstag,
 			input.Zahldatum, // Kommt aus dem gebundenen JSON (ggf. time.Time{})
+			input.Recurrence.rule(),
+			input.labels(),
 		)
 		if err != nil {
 			// Fehler wurde bereits im DAO geloggt, hier nur Antwort senden
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense"}) // Ggf. spezifischere Fehlermeldung
+			respondWithExpenseError(c, err, "Failed to create expense")
 			return
 		}
 
 		// 6. Gebe das erstellte Objekt zurück (Erfolg wurde im DAO geloggt)
-		c.JSON(http.StatusCreated, expense)
+		c.Header("ETag", expenseETag(expense))
+		c.JSON(http.StatusCreated, toExpenseResponse(expense))
 	}
 }
 
@@ -117,7 +159,7 @@ func createExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 // und dass die Logik zur Handhabung der Update-Parameter korrekt ist (siehe vorherige Antwort)
 func updateExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var input models.Haushaltsausgaben
+		var input ExpenseRequest
 		log.Println("[Handler.updateExpense] Attempting to bind JSON body...")
 		if err := c.ShouldBindJSON(&input); err != nil {
 			log.Printf("[Handler.updateExpense] ERROR binding JSON: %v", err)
@@ -134,34 +176,91 @@ func updateExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 		}
 		log.Printf("[Handler.updateExpense] Preparing update for ID: %d with data: %+v", id, input)
 
-		// HIER: Wichtige Überlegung aus vorheriger Antwort anwenden:
-		// Entweder DAO.Update so anpassen, dass es nur die Felder aus 'input' nimmt,
-		// ODER hier das bestehende Objekt laden und nur die relevanten Felder aus 'input'
-		// vor dem Aufruf von DAO.Update setzen.
-		// Der aktuelle DAO.Update erwartet ALLE Felder.
-		// Einfachere Variante für jetzt (sendet ggf. Nullwerte für nicht im JSON vorhandene Felder):
+		// PUT ersetzt die Felder des Eintrags, fehlende werden zu Nullwerten. Ausnahmen:
+		// Ohne recurrence, categories oder tags bleibt der gespeicherte Stand; eine Regel,
+		// die noch dem Typ folgt, wird aus den neuen Werten abgeleitet.
+		// Für Teiländerungen gibt es PATCH mit If-Match.
 		err = expenseDAO.Update(
+			middleware.Principal(c),
 			id,
 			input.Description,
 			input.ValueTotal,
 			input.ValueRate,
 			input.CreditStart,
 			input.CreditEnd,
-			input.Type,   // Typ sollte i.d.R. nicht geändert werden
-			input.UserID, // UserID sollte i.d.R. nicht geändert werden
+			input.Type, // Typ sollte i.d.R. nicht geändert werden
 			input.Faelligkeitstag,
-			input.Receipt, // Receipt kommt nicht aus JSON
+			input.Recurrence.rule(),
+			input.labels(),
 		)
 
 		if err != nil {
 			// Fehler wurde im DAO geloggt
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update expense"})
+			respondWithExpenseError(c, err, "Failed to update expense")
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "Expense updated successfully"})
 	}
 }
 
+// maxPatchBodySize begrenzt die Größe eines Patch-Dokuments
+const maxPatchBodySize = 1 << 20
+
+// patchExpense ändert nur die mitgeschickten Felder (JSON Merge Patch, RFC 7396).
+// If-Match muss die Version aus ETag bzw. Version enthalten; ist der Eintrag
+// inzwischen anderweitig geändert worden, antwortet der Server mit 412.
+func patchExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+			return
+		}
+		if ct := c.ContentType(); ct != mergePatchContentType && ct != "application/json" {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + mergePatchContentType})
+			return
+		}
+		ifMatch := c.GetHeader("If-Match")
+		if ifMatch == "" {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the expense version is required"})
+			return
+		}
+		version, ok := parseIfMatch(ifMatch)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBodySize+1))
+		if err != nil || len(body) > maxPatchBodySize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		// Syntax und Feldnamen vorab prüfen, bevor die Zeile gesperrt wird
+		var probe models.Haushaltsausgaben
+		if err := applyExpensePatch(&probe, body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		expense, err := expenseDAO.Patch(middleware.Principal(c), id, version, func(e *models.Haushaltsausgaben) error {
+			return applyExpensePatch(e, body)
+		})
+		var pe *patchError
+		if errors.As(err, &pe) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to update expense")
+			return
+		}
+
+		c.Header("ETag", expenseETag(expense))
+		c.JSON(http.StatusOK, toExpenseResponse(expense))
+	}
+}
+
 func deleteExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
@@ -171,15 +270,16 @@ func deleteExpense(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 			return
 		}
 
-		err = expenseDAO.Delete(id)
+		err = expenseDAO.Delete(middleware.Principal(c), id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense"})
+			respondWithExpenseError(c, err, "Failed to delete expense")
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "Expense deleted successfully"})
 	}
 }
 
+// getExpensesByUserAndMonth liefert die Fälligkeiten eines Monats (YYYY-MM)
 func getExpensesByUserAndMonth(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDStr := c.Param("userid")
@@ -190,13 +290,50 @@ func getExpensesByUserAndMonth(expenseDAO *dao.HaushaltsausgabenDAO) gin.Handler
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 			return
 		}
+		monthStart, err := time.Parse("2006-01", month)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month, expected YYYY-MM"})
+			return
+		}
+
+		from, to := recurrence.MonthRange(monthStart)
+		occurrences, err := expenseDAO.GetOccurrences(middleware.Principal(c), userID, from, to)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to fetch expenses")
+			return
+		}
+
+		c.JSON(http.StatusOK, toExpenseOccurrenceResponses(occurrences))
+	}
+}
+
+// maxOccurrenceRange begrenzt den Zeitraum einer Abfrage
+const maxOccurrenceRange = 10 * 366 * 24 * time.Hour
 
-		expenses, err := expenseDAO.GetByUserIDAndMonth(userID, month)
+// getExpenseOccurrences liefert die Fälligkeiten im Zeitraum ?from=YYYY-MM-DD&to=YYYY-MM-DD (to exklusiv)
+func getExpenseOccurrences(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("userid"))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		from, errFrom := time.Parse("2006-01-02", c.Query("from"))
+		to, errTo := time.Parse("2006-01-02", c.Query("to"))
+		if errFrom != nil || errTo != nil || !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates (YYYY-MM-DD) with from before to"})
+			return
+		}
+		if to.Sub(from) > maxOccurrenceRange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must not exceed ten years"})
 			return
 		}
 
-		c.JSON(http.StatusOK, expenses)
+		occurrences, err := expenseDAO.GetOccurrences(middleware.Principal(c), userID, from, to)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to fetch expenses")
+			return
+		}
+		c.JSON(http.StatusOK, toExpenseOccurrenceResponses(occurrences))
 	}
 }
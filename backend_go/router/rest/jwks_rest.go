@@ -0,0 +1,17 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterJWKSRoutes publishes the public keys of the access tokens, so other
+// services (e.g. backend_gql) can verify them without sharing a secret.
+func RegisterJWKSRoutes(r *gin.Engine, tokens *auth.TokenManager) {
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": tokens.JWKS()})
+	})
+}
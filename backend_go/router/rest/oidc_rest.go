@@ -0,0 +1,265 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/oidc"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcLoginTTL is how long a user may take to sign in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcStateCookie binds a pending login to the browser that started it. It holds
+// the hash of the state, so the callback only completes logins this browser began
+// (no login CSRF, RFC 6749 section 10.12).
+const oidcStateCookie = "oidc_state"
+
+func RegisterOIDCRoutes(r *gin.Engine, providers map[string]*oidc.Provider, userDAO *dao.UserDAO, oidcDAO *dao.OIDCDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.RefreshTokenDAO, tokens *auth.TokenManager, audit *AuditLog, appBaseURL string) {
+	oidcRoutes := r.Group("/auth/oidc")
+	{
+		oidcRoutes.GET("/providers", listOIDCProviders(providers))
+		// Browser navigation: the user is redirected to the provider and back to the frontend
+		oidcRoutes.GET("/:provider/login", startOIDCLogin(providers, oidcDAO, appBaseURL))
+		oidcRoutes.GET("/:provider/callback", completeOIDCLogin(providers, userDAO, oidcDAO, audit, appBaseURL))
+		// The frontend exchanges the login code from the redirect for a session
+		oidcRoutes.POST("/exchange", exchangeOIDCLoginCode(userDAO, oidcDAO, twoFactorDAO, sessionDAO, tokens, audit))
+	}
+}
+
+// listOIDCProviders returns the configured providers for the login buttons.
+func listOIDCProviders(providers map[string]*oidc.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list := make([]gin.H, 0, len(providers))
+		for _, p := range providers {
+			list = append(list, gin.H{
+				"name":         p.Name,
+				"display_name": p.DisplayName,
+				"login_url":    "/auth/oidc/" + p.Name + "/login",
+			})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i]["name"].(string) < list[j]["name"].(string) })
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func startOIDCLogin(providers map[string]*oidc.Provider, oidcDAO *dao.OIDCDAO, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := providers[c.Param("provider")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+			return
+		}
+
+		nonce, err := auth.NewRandomID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+		verifier, err := oidc.NewCodeVerifier()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+		state, err := oidcDAO.StartLogin(provider.Name, nonce, verifier, oidcLoginTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+
+		authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+		if err != nil {
+			log.Printf("[OIDC] ERROR contacting provider %s: %v", provider.Name, err)
+			redirectOIDCResult(c, appBaseURL, "error", "provider_unavailable")
+			return
+		}
+		setOIDCStateCookie(c, provider, auth.HashToken(state), int(oidcLoginTTL.Seconds()))
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+func completeOIDCLogin(providers map[string]*oidc.Provider, userDAO *dao.UserDAO, oidcDAO *dao.OIDCDAO, audit *AuditLog, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := providers[c.Param("provider")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+			return
+		}
+
+		// The state must belong to a login started in this browser
+		state := c.Query("state")
+		bound, _ := c.Cookie(oidcStateCookie)
+		setOIDCStateCookie(c, provider, "", -1)
+		if state == "" || subtle.ConstantTimeCompare([]byte(bound), []byte(auth.HashToken(state))) != 1 {
+			redirectOIDCResult(c, appBaseURL, "error", "login_expired")
+			return
+		}
+
+		pending, err := oidcDAO.PendingLogin(provider.Name, state)
+		if errors.Is(err, dao.ErrOIDCLoginInvalid) {
+			redirectOIDCResult(c, appBaseURL, "error", "login_expired")
+			return
+		}
+		if err != nil {
+			redirectOIDCResult(c, appBaseURL, "error", "server_error")
+			return
+		}
+
+		fail := func(reason string, details gin.H) {
+			if err := oidcDAO.FailLogin(pending.ID); err != nil {
+				log.Printf("[OIDC] ERROR discarding login %d: %v", pending.ID, err)
+			}
+			if details != nil {
+				details["provider"] = provider.Name
+				details["reason"] = reason
+				audit.Record(c, models.AuditLoginFailed, 0, 0, details)
+			}
+			redirectOIDCResult(c, appBaseURL, "error", reason)
+		}
+
+		// The user cancelled or the provider refused the login
+		if providerErr := c.Query("error"); providerErr != "" {
+			fail("access_denied", gin.H{"provider_error": providerErr})
+			return
+		}
+
+		identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), pending.CodeVerifier, pending.Nonce)
+		if err != nil {
+			log.Printf("[OIDC] ERROR completing login at %s: %v", provider.Name, err)
+			fail("invalid_response", gin.H{})
+			return
+		}
+
+		user, reason, err := resolveOIDCUser(c, provider, userDAO, oidcDAO, audit, identity)
+		if err != nil {
+			log.Printf("[OIDC] ERROR resolving user for %s: %v", provider.Name, err)
+			fail("server_error", nil)
+			return
+		}
+		if user == nil {
+			fail(reason, gin.H{"subject": identity.Subject, "email": identity.Email})
+			return
+		}
+
+		code, err := oidcDAO.CompleteLogin(pending.ID, user.ID)
+		if err != nil {
+			fail("server_error", nil)
+			return
+		}
+		redirectOIDCResult(c, appBaseURL, "code", code)
+	}
+}
+
+// setOIDCStateCookie sets (or with maxAge -1 clears) the state cookie for the
+// provider's callback path. SameSite=Lax still sends it on the top-level redirect
+// back from the provider.
+func setOIDCStateCookie(c *gin.Context, provider *oidc.Provider, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	secure := strings.HasPrefix(provider.RedirectURL, "https://")
+	c.SetCookie(oidcStateCookie, value, maxAge, "/auth/oidc/"+provider.Name, "", secure, true)
+}
+
+// resolveOIDCUser finds the local user for an external identity. Unknown
+// identities are linked to the account with the same verified email, or get a
+// new account if the provider allows it. Without a user, reason explains why.
+func resolveOIDCUser(c *gin.Context, provider *oidc.Provider, userDAO *dao.UserDAO, oidcDAO *dao.OIDCDAO, audit *AuditLog, identity *oidc.Identity) (*models.User, string, error) {
+	linked, err := oidcDAO.GetIdentity(identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, "", err
+	}
+	if linked != nil {
+		if err := oidcDAO.TouchIdentity(linked.ID, identity.Email); err != nil {
+			return nil, "", err
+		}
+		user, err := userDAO.GetByID(linked.UserID)
+		if err != nil || user == nil {
+			return nil, "account_not_found", err
+		}
+		return user, "", nil
+	}
+
+	// Emails are only trusted for matching if the provider verified them
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, "email_not_verified", nil
+	}
+
+	user, err := userDAO.GetByEmail(identity.Email)
+	if err != nil {
+		return nil, "", err
+	}
+	details := gin.H{"provider": provider.Name, "issuer": identity.Issuer, "subject": identity.Subject}
+	switch {
+	case user != nil && !user.IsVerified():
+		// Someone registered the address without confirming it; linking would hand
+		// that account (and its password) to the owner of the email
+		return nil, "account_exists", nil
+	case user != nil:
+		if err := oidcDAO.LinkIdentity(user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+			return nil, "", err
+		}
+		audit.Record(c, models.AuditOIDCIdentityLinked, user.ID, user.ID, details)
+	case provider.AutoProvision:
+		name := identity.Name
+		if name == "" {
+			name = identity.Email
+		}
+		user, err = userDAO.CreateExternal(name, identity.Email)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := oidcDAO.LinkIdentity(user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+			return nil, "", err
+		}
+		audit.Record(c, models.AuditOIDCUserProvisioned, user.ID, user.ID, details)
+	default:
+		return nil, "account_not_found", nil
+	}
+	return user, "", nil
+}
+
+// redirectOIDCResult sends the browser back to the frontend with either a login
+// code or an error reason.
+func redirectOIDCResult(c *gin.Context, appBaseURL, key, value string) {
+	c.Redirect(http.StatusFound, appBaseURL+"/login/oidc?"+url.Values{key: {value}}.Encode())
+}
+
+func exchangeOIDCLoginCode(userDAO *dao.UserDAO, oidcDAO *dao.OIDCDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.RefreshTokenDAO, tokens *auth.TokenManager, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		userID, err := oidcDAO.ConsumeLoginCode(input.Code)
+		if errors.Is(err, dao.ErrOIDCLoginInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem login code"})
+			return
+		}
+
+		user, err := userDAO.GetByID(userID)
+		if err != nil || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+			return
+		}
+
+		// Accounts with 2FA still need the second factor
+		startSession(c, userDAO, twoFactorDAO, sessionDAO, tokens, audit, user, "Login successful")
+	}
+}
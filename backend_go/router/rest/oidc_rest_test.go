@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/oidc"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	providers := map[string]*oidc.Provider{"test": {Config: oidc.Config{Name: "test"}}}
+	r := gin.New()
+	// The DAOs are nil: a rejected state must not get as far as the database
+	r.GET("/auth/oidc/:provider/callback", completeOIDCLogin(providers, nil, nil, nil, "http://app"))
+
+	for name, cookie := range map[string]string{
+		"no cookie":      "",
+		"other state":    auth.HashToken("someone-elses-state"),
+		"plain state":    "attacker-state",
+		"empty for hash": auth.HashToken(""),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/auth/oidc/test/callback?state=attacker-state&code=x", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound || !strings.HasSuffix(w.Header().Get("Location"), "/login/oidc?error=login_expired") {
+			t.Errorf("%s: got %d %s", name, w.Code, w.Header().Get("Location"))
+		}
+		if !strings.Contains(w.Header().Get("Set-Cookie"), oidcStateCookie+"=;") {
+			t.Errorf("%s: state cookie not cleared: %q", name, w.Header().Get("Set-Cookie"))
+		}
+	}
+}
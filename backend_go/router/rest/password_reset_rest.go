@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/mail"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetTokenTTL is how long a mailed reset link stays valid.
+const PasswordResetTokenTTL = time.Hour
+
+func RegisterPasswordResetRoutes(r *gin.Engine, userDAO *dao.UserDAO, resetDAO *dao.PasswordResetDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, mailer mail.Mailer, audit *AuditLog, appBaseURL string) {
+	resetRoutes := r.Group("/users/password-reset")
+	{
+		resetRoutes.POST("/request", requestPasswordReset(userDAO, resetDAO, throttleDAO, mailer, appBaseURL))
+		resetRoutes.POST("/confirm", confirmPasswordReset(userDAO, resetDAO, sessionDAO, throttleDAO, audit))
+	}
+}
+
+// requestPasswordReset mails a reset link if the address belongs to an account.
+// The response is the same either way so it cannot be used to probe for accounts.
+// Requests are throttled per address and per client, whether an account exists or not.
+func requestPasswordReset(userDAO *dao.UserDAO, resetDAO *dao.PasswordResetDAO, throttleDAO *dao.LoginThrottleDAO, mailer mail.Mailer, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		emailKey := dao.PasswordResetThrottleKey(input.Email)
+		ipKey := dao.PasswordResetIPThrottleKey(c.ClientIP())
+		if !checkThrottle(c, throttleDAO, "Too many password reset requests, try again later", emailKey, ipKey) {
+			return
+		}
+		if err := throttleDAO.RecordFailure(emailKey, dao.PasswordResetThrottlePolicy); err != nil {
+			log.Printf("[PasswordReset] ERROR recording request for %s: %v", emailKey, err)
+		}
+		if err := throttleDAO.RecordFailure(ipKey, dao.PasswordResetIPThrottlePolicy); err != nil {
+			log.Printf("[PasswordReset] ERROR recording request for %s: %v", ipKey, err)
+		}
+
+		// Lookup and delivery run in the background so the response time does not depend on them
+		go func(email string) {
+			user, err := userDAO.GetByEmail(email)
+			if err != nil {
+				log.Printf("[PasswordReset] ERROR looking up user: %v", err)
+				return
+			}
+			if user == nil {
+				return
+			}
+
+			token, err := resetDAO.Create(user.ID, PasswordResetTokenTTL)
+			if err != nil {
+				log.Printf("[PasswordReset] ERROR creating token for user %d: %v", user.ID, err)
+				return
+			}
+
+			link := fmt.Sprintf("%s/password-reset?token=%s", appBaseURL, url.QueryEscape(token))
+			err = mailer.Send(mail.Message{
+				To:      user.Email,
+				Subject: "Passwort zurücksetzen",
+				Body: fmt.Sprintf("Hallo %s,\n\nüber folgenden Link kannst du ein neues Passwort setzen:\n\n%s\n\n"+
+					"Der Link ist %d Minuten gültig und kann nur einmal verwendet werden.\n"+
+					"Falls du das nicht angefordert hast, kannst du diese Mail ignorieren.\n",
+					user.Name, link, int(PasswordResetTokenTTL.Minutes())),
+			})
+			if err != nil {
+				log.Printf("[PasswordReset] ERROR sending mail to user %d: %v", user.ID, err)
+			}
+		}(input.Email)
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+	}
+}
+
+// confirmPasswordReset sets a new password with a valid token, ends all sessions
+// of the user and lifts a login lockout of the account.
+func confirmPasswordReset(userDAO *dao.UserDAO, resetDAO *dao.PasswordResetDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required,min=8"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		userID, err := resetDAO.Consume(input.Token)
+		if errors.Is(err, dao.ErrResetTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+
+		if err := userDAO.SetPassword(userID, input.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		audit.Record(c, models.AuditPasswordReset, 0, userID, nil)
+		if err := sessionDAO.RevokeAllForUser(userID); err != nil {
+			log.Printf("[PasswordReset] WARN: could not revoke sessions of user %d: %v", userID, err)
+		}
+		if user, err := userDAO.GetByID(userID); err == nil && user != nil {
+			recordLoginSuccess(throttleDAO, dao.AccountThrottleKey(user.Email))
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+	}
+}
@@ -0,0 +1,219 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/receipt"
+	"backend_go/router/middleware"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxReceiptSize ist die Obergrenze für hochgeladene Belege (RECEIPT_MAX_BYTES)
+const DefaultMaxReceiptSize int64 = 10 << 20
+
+// multipartOverhead deckt Boundary und Header des Formulars ab
+const multipartOverhead = 64 << 10
+
+// receiptExpenseID liest die Ausgaben-ID aus dem Pfad
+func receiptExpenseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// uploadReceipt nimmt einen Beleg als multipart/form-data (Feld "file") an. Der
+// Typ wird aus dem Inhalt bestimmt, erlaubt sind nur PDFs und Bilder.
+func uploadReceipt(expenseDAO *dao.HaushaltsausgabenDAO, maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := receiptExpenseID(c)
+		if !ok {
+			return
+		}
+
+		data, filename, ok := readUploadedFile(c, maxSize)
+		if !ok {
+			return
+		}
+
+		contentType, err := receipt.DetectType(data)
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Ohne Vorschau geht es auch, z.B. bei PDFs oder beschädigten Bildern
+		thumbnail, err := receipt.Thumbnail(data, contentType)
+		if err != nil && !errors.Is(err, receipt.ErrNoThumbnail) {
+			log.Printf("[Handler.uploadReceipt] WARN: no thumbnail for expense ID %d: %v", id, err)
+		}
+
+		expense, err := expenseDAO.SetReceipt(middleware.Principal(c), id, data, contentType, receiptFilename(filename, contentType), thumbnail)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to store receipt")
+			return
+		}
+		c.Header("ETag", expenseETag(expense))
+		c.JSON(http.StatusOK, toExpenseResponse(expense))
+	}
+}
+
+// readUploadedFile liest das Feld "file" eines multipart/form-data-Bodys mit
+// höchstens maxSize Bytes. Bei Fehlern ist die Antwort bereits geschrieben.
+func readUploadedFile(c *gin.Context, maxSize int64) ([]byte, string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return nil, "", false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart form with a 'file' field"})
+		return nil, "", false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return nil, "", false
+	}
+	if int64(len(data)) > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return nil, "", false
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is empty"})
+		return nil, "", false
+	}
+	return data, header.Filename, true
+}
+
+// downloadReceipt streamt den Beleg mit Typ, Dateiname und Range-Unterstützung
+func downloadReceipt(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := receiptExpenseID(c)
+		if !ok {
+			return
+		}
+
+		expense, content, err := expenseDAO.OpenReceipt(middleware.Principal(c), id)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to fetch receipt")
+			return
+		}
+		if content == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Expense has no receipt"})
+			return
+		}
+		defer content.Close()
+
+		// Alte Belege haben keine Metadaten
+		contentType := expense.ReceiptContentType
+		if contentType == "" {
+			head := make([]byte, 512)
+			n, _ := io.ReadFull(content, head)
+			contentType = http.DetectContentType(head[:n])
+			if _, err := content.Seek(0, io.SeekStart); err != nil {
+				log.Printf("[Handler.downloadReceipt] ERROR rewinding receipt of expense ID %d: %v", id, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch receipt"})
+				return
+			}
+		}
+		filename := expense.ReceiptFilename
+		if filename == "" {
+			filename = "beleg-" + strconv.Itoa(expense.ID) + receipt.Extension(contentType)
+		}
+
+		disposition := "attachment"
+		if receipt.Inline(contentType) {
+			disposition = "inline"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "private, no-cache")
+		c.Header("ETag", expenseETag(expense))
+		http.ServeContent(c.Writer, c.Request, filename, expense.ChangedAt, content)
+	}
+}
+
+// getReceiptThumbnail liefert die JPEG-Vorschau eines Bildbelegs
+func getReceiptThumbnail(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := receiptExpenseID(c)
+		if !ok {
+			return
+		}
+
+		thumbnail, err := expenseDAO.GetReceiptThumbnail(middleware.Principal(c), id)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to fetch thumbnail")
+			return
+		}
+		if len(thumbnail) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No thumbnail available"})
+			return
+		}
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "private, no-cache")
+		c.Data(http.StatusOK, "image/jpeg", thumbnail)
+	}
+}
+
+func deleteReceipt(expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := receiptExpenseID(c)
+		if !ok {
+			return
+		}
+
+		expense, err := expenseDAO.DeleteReceipt(middleware.Principal(c), id)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to delete receipt")
+			return
+		}
+		c.Header("ETag", expenseETag(expense))
+		c.JSON(http.StatusOK, toExpenseResponse(expense))
+	}
+}
+
+// receiptFilename bereinigt den Dateinamen des Clients: kein Pfad, keine
+// Steuerzeichen, höchstens 255 Bytes und eine zum Inhalt passende Endung
+func receiptFilename(name, contentType string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	ext := receipt.Extension(contentType)
+	if name == "" || name == "." || name == ".." {
+		return "beleg" + ext
+	}
+	if !strings.EqualFold(filepath.Ext(name), ext) && !(ext == ".jpg" && strings.EqualFold(filepath.Ext(name), ".jpeg")) {
+		name += ext
+	}
+	ext = filepath.Ext(name)
+	for len(name) > 255 {
+		stem := strings.TrimSuffix(name, ext)
+		_, size := utf8.DecodeLastRuneInString(stem)
+		name = stem[:len(stem)-size] + ext
+	}
+	return name
+}
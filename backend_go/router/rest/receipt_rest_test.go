@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"backend_go/receipt"
+	"strings"
+	"testing"
+)
+
+func TestReceiptFilename(t *testing.T) {
+	tests := []struct {
+		name, contentType, want string
+	}{
+		{"Rechnung März.pdf", receipt.TypePDF, "Rechnung März.pdf"},
+		{`C:\Users\max\scan.JPEG`, receipt.TypeJPEG, "scan.JPEG"},
+		{"../../etc/passwd", receipt.TypePDF, "passwd.pdf"},
+		{"foto.png", receipt.TypeJPEG, "foto.png.jpg"},
+		{"a\"b\r\n.pdf", receipt.TypePDF, "ab.pdf"},
+		{"", receipt.TypePNG, "beleg.png"},
+		{"..", receipt.TypePDF, "beleg.pdf"},
+	}
+	for _, tt := range tests {
+		if got := receiptFilename(tt.name, tt.contentType); got != tt.want {
+			t.Errorf("receiptFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReceiptFilenameIsShortened(t *testing.T) {
+	got := receiptFilename(strings.Repeat("ä", 200)+".pdf", receipt.TypePDF)
+	if len(got) > 255 || !strings.HasSuffix(got, ".pdf") || !strings.HasPrefix(got, "ää") {
+		t.Fatalf("unexpected name %q (%d bytes)", got, len(got))
+	}
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/budget"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/recurrence"
+	"backend_go/router/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MonthlySummaryResponse fasst einen Monat zusammen. Alle Beträge zählen die
+// Fälligkeiten des Monats wie die Monatsansicht (Rate, sonst Gesamtbetrag).
+// Fixkosten sind wiederkehrende Ausgaben, variable Kosten einmalige.
+type MonthlySummaryResponse struct {
+	Month          string             `json:"Month"`
+	Income         float64            `json:"Income"`
+	Expenses       float64            `json:"Expenses"`
+	ByType         map[string]float64 `json:"ByType"`
+	FixedCosts     float64            `json:"FixedCosts"`
+	VariableCosts  float64            `json:"VariableCosts"`
+	Surplus        float64            `json:"Surplus"` // negativ bei einem Defizit
+	AccountBalance float64            `json:"AccountBalance"`
+	// ProjectedBalance ist der erwartete Kontostand am Monatsende. Der Kontostand
+	// gilt als Stand zu Beginn des aktuellen Monats, bis zum angefragten Monat
+	// werden die Überschüsse aufaddiert; für vergangene Monate bleibt er leer.
+	ProjectedBalance *float64 `json:"ProjectedBalance"`
+}
+
+// RegisterSummaryRoutes registriert die Monatsübersicht GET /summary/:userid/:month (YYYY-MM)
+func RegisterSummaryRoutes(r *gin.Engine, userDAO *dao.UserDAO, expenseDAO *dao.HaushaltsausgabenDAO, requireAuth, requireVerified gin.HandlerFunc) {
+	summaryRoutes := r.Group("/summary", requireAuth, requireVerified)
+	{
+		summaryRoutes.GET("/:userid/:month", middleware.RequireScope(auth.ScopeExpensesRead), getMonthlySummary(userDAO, expenseDAO))
+	}
+}
+
+func getMonthlySummary(userDAO *dao.UserDAO, expenseDAO *dao.HaushaltsausgabenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param("userid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		month, err := time.Parse("2006-01", c.Param("month"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month, expected YYYY-MM"})
+			return
+		}
+
+		// Für die Prognose werden auch die Monate ab dem aktuellen gebraucht
+		now := time.Now().UTC()
+		from, to := recurrence.MonthRange(month)
+		if currentMonth, _ := recurrence.MonthRange(now); currentMonth.Before(from) {
+			from = currentMonth
+		}
+		if to.Sub(from) > maxOccurrenceRange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Month must not be more than ten years ahead"})
+			return
+		}
+
+		occurrences, err := expenseDAO.GetOccurrences(middleware.Principal(c), userID, from, to)
+		if err != nil {
+			respondWithExpenseError(c, err, "Failed to fetch expenses")
+			return
+		}
+		user, err := userDAO.GetByID(userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+
+		c.JSON(http.StatusOK, buildMonthlySummary(user, occurrences, month, now))
+	}
+}
+
+// buildMonthlySummary berechnet die Übersicht für month. occurrences müssen den
+// Monat und, für die Prognose, alle Monate ab dem aktuellen (now) abdecken.
+func buildMonthlySummary(user *models.User, occurrences []dao.ExpenseOccurrence, month, now time.Time) MonthlySummaryResponse {
+	monthStart, monthEnd := recurrence.MonthRange(month)
+	currentMonth, _ := recurrence.MonthRange(now)
+	income := budget.Round(float64(user.Income))
+	summary := MonthlySummaryResponse{
+		Month:          monthStart.Format("2006-01"),
+		Income:         income,
+		ByType:         map[string]float64{},
+		AccountBalance: budget.Round(float64(user.Accountbalance)),
+	}
+
+	projectedExpenses := 0.0
+	for _, occurrence := range occurrences {
+		amount := occurrence.Expense.DueAmount()
+		if !occurrence.DueDate.Before(currentMonth) && occurrence.DueDate.Before(monthEnd) {
+			projectedExpenses += amount
+		}
+		if occurrence.DueDate.Before(monthStart) || !occurrence.DueDate.Before(monthEnd) {
+			continue
+		}
+		summary.Expenses += amount
+		summary.ByType[occurrence.Expense.Type] += amount
+		if occurrence.Expense.Recurring() {
+			summary.FixedCosts += amount
+		} else {
+			summary.VariableCosts += amount
+		}
+	}
+
+	for typ, amount := range summary.ByType {
+		summary.ByType[typ] = budget.Round(amount)
+	}
+	summary.Expenses = budget.Round(summary.Expenses)
+	summary.FixedCosts = budget.Round(summary.FixedCosts)
+	summary.VariableCosts = budget.Round(summary.VariableCosts)
+	summary.Surplus = budget.Round(income - summary.Expenses)
+
+	if !monthStart.Before(currentMonth) {
+		months := 0
+		for m := currentMonth; m.Before(monthEnd); m = m.AddDate(0, 1, 0) {
+			months++
+		}
+		projected := budget.Round(summary.AccountBalance + float64(months)*income - projectedExpenses)
+		summary.ProjectedBalance = &projected
+	}
+	return summary
+}
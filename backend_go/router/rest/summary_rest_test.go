@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"testing"
+	"time"
+)
+
+func summaryOccurrence(typ, recurrence string, total, rate float64, due time.Time) dao.ExpenseOccurrence {
+	return dao.ExpenseOccurrence{
+		Expense: models.Haushaltsausgaben{Type: typ, Recurrence: recurrence, RecurrenceInterval: 1, ValueTotal: total, ValueRate: rate},
+		DueDate: due,
+	}
+}
+
+func TestBuildMonthlySummary(t *testing.T) {
+	user := &models.User{Income: 3000, Accountbalance: 1200.5}
+	now := time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC)
+	occurrences := []dao.ExpenseOccurrence{
+		summaryOccurrence("monthlycosts", "monthly", 950, 0, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)),
+		summaryOccurrence("credit", "monthly", 12000, 250, time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC)),
+		summaryOccurrence("allelse", "once", 89.99, 0, time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC)),
+		summaryOccurrence("monthlycosts", "monthly", 950, 0, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)),
+		summaryOccurrence("invoice", "once", 4000, 0, time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)),
+	}
+
+	got := buildMonthlySummary(user, occurrences, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), now)
+	if got.Month != "2025-05" || got.Expenses != 1289.99 || got.FixedCosts != 1200 || got.VariableCosts != 89.99 ||
+		got.Surplus != 1710.01 || got.ByType["credit"] != 250 || got.ByType["monthlycosts"] != 950 || len(got.ByType) != 3 {
+		t.Fatalf("unexpected summary: %+v", got)
+	}
+	if got.ProjectedBalance == nil || *got.ProjectedBalance != 2910.51 {
+		t.Fatalf("unexpected projection for the current month: %v", got.ProjectedBalance)
+	}
+
+	// June adds the surpluses of May (1710.01) and June (-1950) to the balance
+	got = buildMonthlySummary(user, occurrences, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), now)
+	if got.Surplus != -1950 || got.ProjectedBalance == nil || *got.ProjectedBalance != 960.51 {
+		t.Fatalf("unexpected summary for a future month: %+v, projected %v", got, got.ProjectedBalance)
+	}
+
+	got = buildMonthlySummary(user, nil, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), now)
+	if got.ProjectedBalance != nil || got.Surplus != 3000 || got.Expenses != 0 {
+		t.Fatalf("unexpected summary for a past month: %+v", got)
+	}
+}
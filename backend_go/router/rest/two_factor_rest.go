@@ -0,0 +1,283 @@
+package rest
+
+import (
+	"backend_go/auth"
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func RegisterTwoFactorRoutes(r *gin.Engine, userDAO *dao.UserDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, tokens *auth.TokenManager, audit *AuditLog, issuer string, requireAuth gin.HandlerFunc) {
+	// Second login step after the password was accepted
+	r.POST("/users/login/2fa", completeTwoFactorLogin(userDAO, twoFactorDAO, sessionDAO, throttleDAO, tokens, audit))
+
+	twoFactorRoutes := r.Group("/users/2fa", requireAuth)
+	{
+		twoFactorRoutes.GET("", getTwoFactorStatus(twoFactorDAO))
+		twoFactorRoutes.POST("/enroll", enrollTwoFactor(userDAO, twoFactorDAO, issuer))
+		twoFactorRoutes.POST("/enable", enableTwoFactor(twoFactorDAO, audit))
+		twoFactorRoutes.POST("/disable", disableTwoFactor(userDAO, twoFactorDAO, throttleDAO, audit))
+		twoFactorRoutes.POST("/recovery-codes", regenerateRecoveryCodes(twoFactorDAO, throttleDAO))
+	}
+}
+
+// startSession finishes a password login. Users with 2FA get a short-lived MFA
+// token that has to be exchanged at /users/login/2fa, everyone else gets a session.
+func startSession(c *gin.Context, userDAO *dao.UserDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.RefreshTokenDAO, tokens *auth.TokenManager, audit *AuditLog, user *models.User, message string) {
+	enabled, err := twoFactorDAO.IsEnabled(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if !enabled {
+		respondWithToken(c, userDAO, audit, sessionDAO, tokens, user, message)
+		return
+	}
+
+	mfaToken, expiresAt, err := tokens.IssueMFAToken(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Second factor required",
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+		"expires_in":   int(time.Until(expiresAt).Seconds()),
+	})
+}
+
+func completeTwoFactorLogin(userDAO *dao.UserDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, tokens *auth.TokenManager, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+			Code     string `json:"code" binding:"required"` // TOTP or recovery code
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		claims, err := tokens.ParseMFAToken(input.MFAToken)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login attempt"})
+			return
+		}
+		userID, _ := claims.UserID()
+
+		// Codes are guessed the same way as passwords, so they share the lockout
+		userKey := dao.UserThrottleKey(userID)
+		if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), userKey) {
+			return
+		}
+
+		err = twoFactorDAO.Verify(userID, input.Code)
+		if errors.Is(err, dao.ErrTwoFactorCodeInvalid) || errors.Is(err, dao.ErrTwoFactorNotEnrolled) {
+			recordLoginFailure(c, throttleDAO, userKey)
+			audit.Record(c, models.AuditLoginFailed, 0, userID, gin.H{"reason": "second_factor"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+		recordLoginSuccess(throttleDAO, userKey)
+
+		user, err := userDAO.GetByID(userID)
+		if err != nil || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		respondWithToken(c, userDAO, audit, sessionDAO, tokens, user, "Login successful")
+	}
+}
+
+func getTwoFactorStatus(twoFactorDAO *dao.TwoFactorDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.UserID(c)
+		enabled, err := twoFactorDAO.IsEnabled(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+			return
+		}
+
+		status := gin.H{"enabled": enabled}
+		if enabled {
+			remaining, err := twoFactorDAO.RemainingRecoveryCodes(userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+				return
+			}
+			status["recovery_codes_remaining"] = remaining
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// enrollTwoFactor creates a pending TOTP secret. It only becomes active after /enable.
+func enrollTwoFactor(userDAO *dao.UserDAO, twoFactorDAO *dao.TwoFactorDAO, issuer string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userDAO.GetByID(middleware.UserID(c))
+		if err != nil || user == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+
+		secret, err := twoFactorDAO.StartEnrollment(user.ID)
+		if errors.Is(err, dao.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+			return
+		}
+
+		uri := auth.TOTPProvisioningURI(secret, issuer, user.Email)
+		png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"secret":           secret,
+			"provisioning_uri": uri,
+			"qr_payload":       uri,
+			"qr_png":           "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		})
+	}
+}
+
+// enableTwoFactor activates 2FA with a first code and returns the recovery codes once.
+func enableTwoFactor(twoFactorDAO *dao.TwoFactorDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		codes, err := twoFactorDAO.Enable(middleware.UserID(c), input.Code)
+		switch {
+		case errors.Is(err, dao.ErrTwoFactorNotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start the enrollment first"})
+			return
+		case errors.Is(err, dao.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		case errors.Is(err, dao.ErrTwoFactorCodeInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+		audit.Record(c, models.AuditTwoFactorEnabled, middleware.UserID(c), middleware.UserID(c), nil)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Two-factor authentication enabled",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// disableTwoFactor requires the password and a current code or recovery code.
+// Both can be guessed with a stolen access token, so they share the login lockout.
+func disableTwoFactor(userDAO *dao.UserDAO, twoFactorDAO *dao.TwoFactorDAO, throttleDAO *dao.LoginThrottleDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Password string `json:"password" binding:"required"`
+			Code     string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		userID := middleware.UserID(c)
+		userKey := dao.UserThrottleKey(userID)
+		if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), userKey) {
+			return
+		}
+
+		ok, err := userDAO.CheckPassword(userID, input.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password"})
+			return
+		}
+		if !ok {
+			recordLoginFailure(c, throttleDAO, userKey)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		if err := twoFactorDAO.Verify(userID, input.Code); err != nil {
+			if errors.Is(err, dao.ErrTwoFactorCodeInvalid) || errors.Is(err, dao.ErrTwoFactorNotEnrolled) {
+				recordLoginFailure(c, throttleDAO, userKey)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+		recordLoginSuccess(throttleDAO, userKey)
+
+		if err := twoFactorDAO.Disable(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+		audit.Record(c, models.AuditTwoFactorDisabled, userID, userID, nil)
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// regenerateRecoveryCodes hands out new recovery codes for a current code. Guesses
+// count towards the login lockout like those at /users/login/2fa.
+func regenerateRecoveryCodes(twoFactorDAO *dao.TwoFactorDAO, throttleDAO *dao.LoginThrottleDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		userID := middleware.UserID(c)
+		userKey := dao.UserThrottleKey(userID)
+		if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), userKey) {
+			return
+		}
+
+		if err := twoFactorDAO.Verify(userID, input.Code); err != nil {
+			if errors.Is(err, dao.ErrTwoFactorCodeInvalid) || errors.Is(err, dao.ErrTwoFactorNotEnrolled) {
+				recordLoginFailure(c, throttleDAO, userKey)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+		recordLoginSuccess(throttleDAO, userKey)
+
+		codes, err := twoFactorDAO.RegenerateRecoveryCodes(userID)
+		if err != nil {
+			log.Printf("[TwoFactor] ERROR regenerating recovery codes for user %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
@@ -0,0 +1,62 @@
+package rest
+
+import "backend_go/db/models"
+
+// CreateUserRequest is the body of POST /users. ID and balances cannot be set on registration.
+type CreateUserRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Username string `json:"username"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// UpdateUserRequest is the body of PUT /users/:id. An empty password keeps the
+// current one, a new one needs current_password; omitted income and balance stay
+// unchanged.
+type UpdateUserRequest struct {
+	Name            string   `json:"name" binding:"required"`
+	Username        *string  `json:"username"`
+	Email           string   `json:"email" binding:"required,email"`
+	Password        string   `json:"password" binding:"omitempty,min=8"`
+	CurrentPassword string   `json:"current_password"`
+	Income          *float32 `json:"income"`
+	Accountbalance  *float32 `json:"accountbalance"`
+}
+
+// UserResponse is the public representation of a user.
+type UserResponse struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Username       string  `json:"username,omitempty"`
+	Email          string  `json:"email"`
+	Income         float32 `json:"income"`
+	Accountbalance float32 `json:"accountbalance"`
+	Verified       bool    `json:"verified"`
+	Role           string  `json:"role"`
+}
+
+// toUserResponse maps a user model to its API representation.
+func toUserResponse(user *models.User) UserResponse {
+	resp := UserResponse{
+		ID:             user.ID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Income:         user.Income,
+		Accountbalance: user.Accountbalance,
+		Verified:       user.IsVerified(),
+		Role:           user.Role,
+	}
+	if user.Username != nil {
+		resp.Username = *user.Username
+	}
+	return resp
+}
+
+// toUserResponses maps a list of user models.
+func toUserResponses(users []models.User) []UserResponse {
+	result := make([]UserResponse, 0, len(users))
+	for i := range users {
+		result = append(result, toUserResponse(&users[i]))
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"archive/zip"
+	"backend_go/db/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportProfile is the profile part of a personal data export. Unlike
+// UserResponse it contains everything stored about the account except secrets.
+type exportProfile struct {
+	UserResponse
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	ExportedAt          time.Time  `json:"exported_at"`
+}
+
+// exportExpense is an expense as written to expenses.json, with the file name
+// of its receipt inside the archive.
+type exportExpense struct {
+	ExpenseResponse
+	ReceiptFile string `json:"ReceiptFile,omitempty"`
+}
+
+var expenseCSVHeader = []string{
+	"ID", "Description", "ValueTotal", "ValueRate", "CreditStart", "CreditEnd", "Type",
+	"Faelligkeitstag", "Zahldatum", "CreatedAt", "ChangedAt",
+	"Recurrence", "RecurrenceInterval", "RecurrenceStart", "RecurrenceEnd", "Categories", "Tags", "ReceiptFile",
+}
+
+// writeUserExport writes a ZIP archive with profile.json, expenses.json,
+// expenses.csv and one file per receipt below receipts/.
+func writeUserExport(w io.Writer, user *models.User, expenses []models.Haushaltsausgaben, now time.Time) error {
+	zw := zip.NewWriter(w)
+
+	profile := exportProfile{
+		UserResponse:        toUserResponse(user),
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		ExportedAt:          now,
+	}
+	if err := writeZipJSON(zw, "profile.json", now, profile); err != nil {
+		return err
+	}
+
+	entries := make([]exportExpense, 0, len(expenses))
+	for i := range expenses {
+		entry := exportExpense{ExpenseResponse: toExpenseResponse(&expenses[i])}
+		if len(expenses[i].Receipt) > 0 {
+			entry.ReceiptFile = "receipts/" + strconv.Itoa(expenses[i].ID) + receiptExtension(expenses[i].Receipt)
+			if err := writeZipFile(zw, entry.ReceiptFile, now, expenses[i].Receipt); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := writeZipJSON(zw, "expenses.json", now, entries); err != nil {
+		return err
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "expenses.csv", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(expenseCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			strconv.Itoa(e.ID),
+			e.Description,
+			strconv.FormatFloat(e.ValueTotal, 'f', 2, 64),
+			strconv.FormatFloat(e.ValueRate, 'f', 2, 64),
+			formatExportDate(e.CreditStart),
+			formatExportDate(e.CreditEnd),
+			e.Type,
+			e.Faelligkeitstag,
+			formatExportDate(e.Zahldatum),
+			e.CreatedAt.Format(time.RFC3339),
+			e.ChangedAt.Format(time.RFC3339),
+			e.Recurrence.Frequency,
+			strconv.Itoa(e.Recurrence.Interval),
+			formatExportDate(e.Recurrence.Start),
+			formatOptionalExportDate(e.Recurrence.End),
+			joinCategoryNames(e.Categories),
+			strings.Join(e.Tags, "; "),
+			e.ReceiptFile,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func joinCategoryNames(categories []CategoryRef) string {
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+func writeZipJSON(zw *zip.Writer, name string, modified time.Time, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return writeZipFile(zw, name, modified, data)
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// receiptExtension guesses a file extension from the receipt content.
+func receiptExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "application/pdf":
+		return ".pdf"
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "text/xml; charset=utf-8":
+		return ".xml"
+	default:
+		return ".bin"
+	}
+}
+
+// formatExportDate formats a date column; unset dates stay empty.
+func formatExportDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatOptionalExportDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatExportDate(*t)
+}
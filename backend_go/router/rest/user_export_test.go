@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"archive/zip"
+	"backend_go/db/models"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteUserExport(t *testing.T) {
+	user := &models.User{ID: 7, Name: "Tobi", Email: "tobi@example.com", Password: "$2a$12$secrethash"}
+	expenses := []models.Haushaltsausgaben{
+		{ID: 1, Description: "Miete", ValueRate: 850, Type: "monthlycosts", UserID: 7},
+		{ID: 2, Description: "Laptop", ValueTotal: 1200, Type: "allelse", UserID: 7, Receipt: []byte("%PDF-1.4 receipt")},
+	}
+
+	var buf bytes.Buffer
+	if err := writeUserExport(&buf, user, expenses, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"profile.json", "expenses.json", "expenses.csv", "receipts/2.pdf"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("archive misses %s, has %v", name, files)
+		}
+	}
+	if strings.Contains(files["profile.json"], "secrethash") {
+		t.Fatalf("profile leaks the password: %s", files["profile.json"])
+	}
+	if files["receipts/2.pdf"] != "%PDF-1.4 receipt" {
+		t.Fatalf("unexpected receipt content: %q", files["receipts/2.pdf"])
+	}
+	if lines := strings.Split(strings.TrimSpace(files["expenses.csv"]), "\n"); len(lines) != 3 || !strings.HasSuffix(lines[2], "receipts/2.pdf") {
+		t.Fatalf("unexpected csv: %s", files["expenses.csv"])
+	}
+}
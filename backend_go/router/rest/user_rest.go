@@ -1,31 +1,170 @@
 package rest
 
 import (
+	"backend_go/auth"
 	"backend_go/db/dao"
 	"backend_go/db/models"
+	"backend_go/router/middleware"
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func RegisterUserRoutes(r *gin.Engine, userDAO *dao.UserDAO) {
+func RegisterUserRoutes(r *gin.Engine, userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, twoFactorDAO *dao.TwoFactorDAO, throttleDAO *dao.LoginThrottleDAO, tokens *auth.TokenManager, verifier *EmailVerifier, audit *AuditLog, requireAuth, requireAdmin gin.HandlerFunc) {
 	userRoutes := r.Group("/users")
 	{
-		userRoutes.POST("/", createUser(userDAO))
-		userRoutes.GET("/", getUsers(userDAO))
-		userRoutes.PUT("/:id", updateUser(userDAO))
-		userRoutes.DELETE("/:id", deleteUser(userDAO))
-		userRoutes.POST("/login", login(userDAO))
-		userRoutes.POST("/authenticate", authenticateUser(userDAO))
-		userRoutes.GET("/:identifier", getUser(userDAO))
+		userRoutes.POST("/", createUser(userDAO, verifier))
+		userRoutes.POST("/login", login(userDAO, sessionDAO, twoFactorDAO, throttleDAO, tokens, audit))
+		userRoutes.POST("/authenticate", authenticateUser(userDAO, sessionDAO, twoFactorDAO, throttleDAO, tokens, audit))
+		userRoutes.POST("/refresh", refreshSession(userDAO, sessionDAO, tokens))
+		userRoutes.POST("/logout", logout(sessionDAO, audit))
+	}
 
+	// Everything else requires a valid access token
+	protected := userRoutes.Group("", requireAuth)
+	{
+		protected.POST("/logout-all", logoutAll(sessionDAO, audit))
+		protected.GET("/sessions", getSessions(sessionDAO))
+		protected.DELETE("/sessions/:sessionId", revokeSession(sessionDAO, audit))
+		protected.GET("/", requireAdmin, getUsers(userDAO))
+		protected.PUT("/:id", updateUser(userDAO, sessionDAO, throttleDAO, verifier, audit))
+		protected.DELETE("/:id", requireAdmin, deleteUser(userDAO, audit))
+		protected.GET("/:identifier", getUser(userDAO))
 	}
 }
 
-func createUser(userDAO *dao.UserDAO) gin.HandlerFunc {
+// checkLoginThrottle answers with 429 and a Retry-After header if any of the
+// keys is locked after too many failed attempts. It returns false in that case.
+func checkLoginThrottle(c *gin.Context, throttleDAO *dao.LoginThrottleDAO, keys ...string) bool {
+	return checkThrottle(c, throttleDAO, "Too many failed login attempts, try again later", keys...)
+}
+
+// checkThrottle answers with 429 and the given message if one of the keys is locked.
+func checkThrottle(c *gin.Context, throttleDAO *dao.LoginThrottleDAO, message string, keys ...string) bool {
+	wait, err := throttleDAO.RetryAfter(keys...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// recordLoginFailure counts a failed attempt for the client address and the account key.
+func recordLoginFailure(c *gin.Context, throttleDAO *dao.LoginThrottleDAO, accountKey string) {
+	if err := throttleDAO.RecordFailure(dao.IPThrottleKey(c.ClientIP()), dao.IPThrottlePolicy); err != nil {
+		log.Printf("[LoginThrottle] ERROR recording failure for %s: %v", c.ClientIP(), err)
+	}
+	if err := throttleDAO.RecordFailure(accountKey, dao.AccountThrottlePolicy); err != nil {
+		log.Printf("[LoginThrottle] ERROR recording failure for %s: %v", accountKey, err)
+	}
+}
+
+// recordLoginSuccess resets the failure counter of the account.
+func recordLoginSuccess(throttleDAO *dao.LoginThrottleDAO, accountKey string) {
+	if err := throttleDAO.Clear(accountKey); err != nil {
+		log.Printf("[LoginThrottle] ERROR clearing %s: %v", accountKey, err)
+	}
+}
+
+// respondWithToken starts a new session for the user and writes the login response
+// containing a short-lived access token and a rotating refresh token.
+func respondWithToken(c *gin.Context, userDAO *dao.UserDAO, audit *AuditLog, sessionDAO *dao.RefreshTokenDAO, tokens *auth.TokenManager, user *models.User, message string) {
+	body := gin.H{
+		"message":  message,
+		"user_id":  user.ID,
+		"name":     user.Name,
+		"verified": user.IsVerified(),
+		"role":     user.Role,
+	}
+
+	// Logging in during the grace period keeps the account
+	if user.DeletionScheduledAt != nil {
+		if err := userDAO.CancelDeletion(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+			return
+		}
+		body["deletion_cancelled"] = true
+		audit.Record(c, models.AuditAccountDeletionCancelled, user.ID, user.ID, nil)
+	}
+
+	refreshToken, session, err := sessionDAO.Create(user.ID, c.Request.UserAgent(), tokens.RefreshTokenTTL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	audit.Record(c, models.AuditLoginSucceeded, user.ID, user.ID, gin.H{"session_id": session.FamilyID})
+	writeTokenPair(c, tokens, user.ID, session.FamilyID, user.Role, refreshToken, body)
+}
+
+// writeTokenPair issues an access token for the session and adds both tokens to body.
+func writeTokenPair(c *gin.Context, tokens *auth.TokenManager, userID int, sessionID, role, refreshToken string, body gin.H) {
+	accessToken, expiresAt, err := tokens.IssueAccessToken(userID, sessionID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		return
+	}
+
+	body["access_token"] = accessToken
+	body["token_type"] = "Bearer"
+	body["expires_in"] = int(time.Until(expiresAt).Seconds())
+	body["refresh_token"] = refreshToken
+	c.JSON(http.StatusOK, body)
+}
+
+// checkUsernameAvailable validates the username and makes sure no other user
+// (apart from ownID) has it. It writes a 400 response and returns false otherwise.
+func checkUsernameAvailable(c *gin.Context, userDAO *dao.UserDAO, username string, ownID int) bool {
+	if err := dao.ValidateUsername(username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	existing, err := userDAO.GetByUsername(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check if username exists"})
+		return false
+	}
+	if existing != nil && existing.ID != ownID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is already taken"})
+		return false
+	}
+	return true
+}
+
+// checkEmailAvailable answers with 400 if another account uses the email
+// (case-insensitive, like the unique index). It returns false in that case.
+func checkEmailAvailable(c *gin.Context, userDAO *dao.UserDAO, email string, ownID int) bool {
+	existing, err := userDAO.GetByEmail(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check if email exists"})
+		return false
+	}
+	if existing != nil && existing.ID != ownID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already registered"})
+		return false
+	}
+	return true
+}
+
+func createUser(userDAO *dao.UserDAO, verifier *EmailVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var input models.User
+		var input CreateUserRequest
 		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -42,13 +181,21 @@ func createUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 			return
 		}
 
+		// The username is optional, but must be valid and unique if given
+		if input.Username != "" && !checkUsernameAvailable(c, userDAO, input.Username, 0) {
+			return
+		}
+
 		// Call DAO method with password included
-		user, err := userDAO.Create(input.Name, input.Email, input.Password)
+		user, err := userDAO.Create(input.Name, input.Username, input.Email, input.Password)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 			return
 		}
-		c.JSON(http.StatusCreated, user)
+		// The account stays read-only until the address is confirmed
+		verifier.sendAsync(*user)
+
+		c.JSON(http.StatusCreated, toUserResponse(user))
 	}
 }
 
@@ -59,13 +206,15 @@ func getUsers(userDAO *dao.UserDAO) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 			return
 		}
-		c.JSON(http.StatusOK, users)
+		c.JSON(http.StatusOK, toUserResponses(users))
 	}
 }
 
-func updateUser(userDAO *dao.UserDAO) gin.HandlerFunc {
+// updateUser changes the caller's account. A new password needs the current one,
+// so a stolen access token cannot take the account over, and ends all other sessions.
+func updateUser(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, throttleDAO *dao.LoginThrottleDAO, verifier *EmailVerifier, audit *AuditLog) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var input models.User
+		var input UpdateUserRequest
 		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -79,18 +228,87 @@ func updateUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Users may only change their own account
+		if id != middleware.UserID(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to modify other users"})
+			return
+		}
+
+		current, err := userDAO.GetByID(id)
+		if err != nil || current == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+
+		if input.Username != nil && !checkUsernameAvailable(c, userDAO, *input.Username, id) {
+			return
+		}
+		emailChanged := !strings.EqualFold(current.Email, input.Email)
+		if emailChanged && !checkEmailAvailable(c, userDAO, input.Email, id) {
+			return
+		}
+		if input.Password != "" && !checkCurrentPassword(c, userDAO, throttleDAO, id, input.CurrentPassword) {
+			return
+		}
+
 		// Update user details (including password if provided)
-		err = userDAO.Update(id, input.Name, input.Email, input.Password)
+		err = userDAO.Update(id, dao.UserChanges{
+			Name:           input.Name,
+			Email:          input.Email,
+			Password:       input.Password,
+			Username:       input.Username,
+			Income:         input.Income,
+			Accountbalance: input.Accountbalance,
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 			return
 		}
+		if input.Password != "" {
+			audit.Record(c, models.AuditPasswordChanged, id, id, nil)
+			if err := sessionDAO.RevokeOtherSessions(id, middleware.SessionID(c)); err != nil {
+				log.Printf("[UpdateUser] WARN: could not revoke other sessions of user %d: %v", id, err)
+			}
+		}
+
+		// Update reset the verification, the new address has to be confirmed
+		if emailChanged {
+			updated := *current
+			updated.Name, updated.Email = input.Name, input.Email
+			verifier.sendAsync(updated)
+		}
 
 		c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 	}
 }
 
-func deleteUser(userDAO *dao.UserDAO) gin.HandlerFunc {
+// checkCurrentPassword confirms the caller's password before it is changed. Wrong
+// guesses count towards the login lockout; the response is written on failure.
+func checkCurrentPassword(c *gin.Context, userDAO *dao.UserDAO, throttleDAO *dao.LoginThrottleDAO, id int, password string) bool {
+	if password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is required to set a new one"})
+		return false
+	}
+	userKey := dao.UserThrottleKey(id)
+	if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), userKey) {
+		return false
+	}
+	ok, err := userDAO.CheckPassword(id, password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password"})
+		return false
+	}
+	if !ok {
+		recordLoginFailure(c, throttleDAO, userKey)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid current password"})
+		return false
+	}
+	recordLoginSuccess(throttleDAO, userKey)
+	return true
+}
+
+// deleteUser lets an admin delete another account immediately.
+func deleteUser(userDAO *dao.UserDAO, audit *AuditLog) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 		id, err := strconv.Atoi(idStr)
@@ -99,17 +317,46 @@ func deleteUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Admins only; the own account goes through DELETE /users/me with password
+		// confirmation and the deletion grace period
+		if id == middleware.UserID(c) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Use DELETE /users/me to delete your own account"})
+			return
+		}
+
 		err = userDAO.Delete(id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			case errors.Is(err, dao.ErrLastAdmin):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			}
 			return
 		}
+		audit.Record(c, models.AuditAccountDeleted, middleware.UserID(c), id, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 	}
 }
 
-func login(userDAO *dao.UserDAO) gin.HandlerFunc {
+// loginThrottleKey returns the account throttle key for a login identifier. Known
+// accounts are keyed by their email, so logins by email and by username share one
+// counter, which password reset and `unlock <email>` clear as well.
+func loginThrottleKey(userDAO *dao.UserDAO, identifier string) (string, error) {
+	user, err := userDAO.GetByLogin(identifier)
+	if err != nil {
+		return "", err
+	}
+	if user != nil {
+		return dao.AccountThrottleKey(user.Email), nil
+	}
+	return dao.AccountThrottleKey(identifier), nil
+}
+
+func login(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, twoFactorDAO *dao.TwoFactorDAO, throttleDAO *dao.LoginThrottleDAO, tokens *auth.TokenManager, audit *AuditLog) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var input struct {
 			Email    string `json:"email"`
@@ -121,32 +368,39 @@ func login(userDAO *dao.UserDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Check if the user exists by email
-		user, err := userDAO.GetByEmail(input.Email)
+		// Refuse attempts while the client or the account is locked
+		accountKey, err := loginThrottleKey(userDAO, input.Email)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate user"})
+			return
+		}
+		if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), accountKey) {
 			return
 		}
 
-		// If user does not exist
-		if user == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		// Check the credentials; plaintext passwords are upgraded to a hash on success
+		user, err := userDAO.AuthenticateUser(input.Email, input.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
 			return
 		}
 
-		// Check if the passwords match (no hashing)
-		if user.Password != input.Password {
+		// If user does not exist or the password does not match
+		if user == nil {
+			recordLoginFailure(c, throttleDAO, accountKey)
+			audit.Record(c, models.AuditLoginFailed, 0, 0, gin.H{"identifier": input.Email})
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
+		recordLoginSuccess(throttleDAO, accountKey)
 
-		// If everything matches, send success response (no JWT)
-		c.JSON(http.StatusOK, gin.H{"message": "Login successful"})
+		// If everything matches, issue tokens (or ask for the second factor)
+		startSession(c, userDAO, twoFactorDAO, sessionDAO, tokens, audit, user, "Login successful")
 	}
 }
 
 // New authentication endpoint that checks both email and username
-func authenticateUser(userDAO *dao.UserDAO) gin.HandlerFunc {
+func authenticateUser(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, twoFactorDAO *dao.TwoFactorDAO, throttleDAO *dao.LoginThrottleDAO, tokens *auth.TokenManager, audit *AuditLog) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var input struct {
 			Identifier string `json:"identifier"` // Can be email or username
@@ -158,6 +412,16 @@ func authenticateUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Refuse attempts while the client or the account is locked
+		accountKey, err := loginThrottleKey(userDAO, input.Identifier)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate user"})
+			return
+		}
+		if !checkLoginThrottle(c, throttleDAO, dao.IPThrottleKey(c.ClientIP()), accountKey) {
+			return
+		}
+
 		// Authenticate user by email or username
 		user, err := userDAO.AuthenticateUser(input.Identifier, input.Password)
 		if err != nil {
@@ -167,16 +431,16 @@ func authenticateUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 
 		// If user not found
 		if user == nil {
+			recordLoginFailure(c, throttleDAO, accountKey)
+			audit.Record(c, models.AuditLoginFailed, 0, 0, gin.H{"identifier": input.Identifier})
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
+		recordLoginSuccess(throttleDAO, accountKey)
 
-		// Return user ID and name upon successful authentication
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Authentication successful",
-			"user_id": user.ID,
-			"name":    user.Name,
-		})
+		// Return user ID, name and tokens upon successful authentication,
+		// or an MFA token if the user has two-factor authentication enabled
+		startSession(c, userDAO, twoFactorDAO, sessionDAO, tokens, audit, user, "Authentication successful")
 	}
 }
 
@@ -184,12 +448,142 @@ func getUser(userDAO *dao.UserDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		identifier := c.Param("identifier")
 
-		user, err := userDAO.GetByIDOrEmail(identifier)
+		user, err := userDAO.GetByIdentifier(identifier)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
 		}
 
-		c.JSON(http.StatusOK, user)
+		// Other accounts are only visible to admins; members get the same 404
+		// as for unknown users so existence is not revealed
+		if user.ID != middleware.UserID(c) && !middleware.Principal(c).IsAdmin() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toUserResponse(user))
+	}
+}
+
+// refreshSession exchanges a refresh token for a new token pair. Every refresh
+// token can be used once; presenting it again revokes the whole session.
+func refreshSession(userDAO *dao.UserDAO, sessionDAO *dao.RefreshTokenDAO, tokens *auth.TokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		refreshToken, next, err := sessionDAO.Rotate(input.RefreshToken, c.Request.UserAgent(), tokens.RefreshTokenTTL())
+		if errors.Is(err, dao.ErrRefreshTokenInvalid) || errors.Is(err, dao.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			return
+		}
+
+		// The role is read again so the new access token reflects the current one
+		user, err := userDAO.GetByID(next.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+
+		writeTokenPair(c, tokens, user.ID, next.FamilyID, user.Role, refreshToken, gin.H{
+			"message": "Session refreshed",
+			"user_id": next.UserID,
+		})
+	}
+}
+
+// logout revokes the session the given refresh token belongs to.
+func logout(sessionDAO *dao.RefreshTokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		token, err := sessionDAO.GetByToken(input.RefreshToken)
+		if errors.Is(err, dao.ErrRefreshTokenInvalid) {
+			// Nothing to revoke, the client is logged out either way
+			c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+
+		if err := sessionDAO.RevokeFamily(token.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		audit.Record(c, models.AuditSessionRevoked, token.UserID, token.UserID, gin.H{"session_id": token.FamilyID, "reason": "logout"})
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+// logoutAll revokes every session of the caller.
+func logoutAll(sessionDAO *dao.RefreshTokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := sessionDAO.RevokeAllForUser(middleware.UserID(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		audit.Record(c, models.AuditSessionRevoked, middleware.UserID(c), middleware.UserID(c), gin.H{"reason": "logout_all"})
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out on all devices"})
+	}
+}
+
+func getSessions(sessionDAO *dao.RefreshTokenDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions, err := sessionDAO.ListSessions(middleware.UserID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+			return
+		}
+
+		current := middleware.SessionID(c)
+		result := make([]gin.H, 0, len(sessions))
+		for _, s := range sessions {
+			result = append(result, gin.H{
+				"id":             s.ID,
+				"user_agent":     s.UserAgent,
+				"last_active_at": s.LastActiveAt,
+				"expires_at":     s.ExpiresAt,
+				"current":        s.ID == current,
+			})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// revokeSession ends one session of the caller, e.g. for a lost phone.
+func revokeSession(sessionDAO *dao.RefreshTokenDAO, audit *AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := sessionDAO.RevokeUserFamily(middleware.UserID(c), c.Param("sessionId"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		audit.Record(c, models.AuditSessionRevoked, middleware.UserID(c), middleware.UserID(c), gin.H{"session_id": c.Param("sessionId")})
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
 	}
 }
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"backend_go/db"
+	"backend_go/db/dao"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it; without it the test is skipped.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestLoginThrottleSetsRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	throttle := dao.NewLoginThrottleDAO(testDB(t))
+	key := dao.AccountThrottleKey(fmt.Sprintf("retry-%d@example.com", time.Now().UnixNano()))
+	t.Cleanup(func() { throttle.Clear(key) })
+
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		if checkLoginThrottle(c, throttle, key) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	try := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		return w
+	}
+
+	if w := try(); w.Code != http.StatusNoContent || w.Header().Get("Retry-After") != "" {
+		t.Fatalf("unlocked key: got %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	for i := 0; i < dao.AccountThrottlePolicy.Threshold; i++ {
+		if err := throttle.RecordFailure(key, dao.AccountThrottlePolicy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := try()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked key: got %d", w.Code)
+	}
+	seconds, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || seconds <= 0 || seconds > int(dao.AccountThrottlePolicy.BaseDelay.Seconds()) {
+		t.Fatalf("Retry-After = %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestPasswordResetRequestsAreThrottled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := testDB(t)
+	throttle := dao.NewLoginThrottleDAO(database)
+	email := fmt.Sprintf("reset-%d@example.com", time.Now().UnixNano())
+	ipKey := dao.PasswordResetIPThrottleKey("192.0.2.1") // httptest's client address
+	throttle.Clear(ipKey)
+	t.Cleanup(func() {
+		throttle.Clear(dao.PasswordResetThrottleKey(email))
+		throttle.Clear(ipKey)
+	})
+
+	r := gin.New()
+	// No account has the address, so no mail is sent
+	r.POST("/request", requestPasswordReset(dao.NewUserDAO(database, nil), nil, throttle, nil, "http://app"))
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/request", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < dao.PasswordResetThrottlePolicy.Threshold; i++ {
+		if w := request(); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status %d", i+1, w.Code)
+		}
+	}
+	w := request()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("over the limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// The login of the address is not affected
+	if wait, err := throttle.RetryAfter(dao.AccountThrottleKey(email)); err != nil || wait != 0 {
+		t.Fatalf("account locked for %v, %v", wait, err)
+	}
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"backend_go/db/dao"
+	"backend_go/db/models"
+	"backend_go/mail"
+	"backend_go/router/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailVerificationTokenTTL is how long a mailed verification link stays valid.
+const EmailVerificationTokenTTL = 48 * time.Hour
+
+// EmailVerifier creates verification tokens and mails the confirmation link.
+type EmailVerifier struct {
+	verificationDAO *dao.EmailVerificationDAO
+	mailer          mail.Mailer
+	apiBaseURL      string
+}
+
+// NewEmailVerifier creates an EmailVerifier whose links point to the API at apiBaseURL.
+func NewEmailVerifier(verificationDAO *dao.EmailVerificationDAO, mailer mail.Mailer, apiBaseURL string) *EmailVerifier {
+	return &EmailVerifier{verificationDAO: verificationDAO, mailer: mailer, apiBaseURL: apiBaseURL}
+}
+
+// Send mails a new verification link to the user.
+func (v *EmailVerifier) Send(user *models.User) error {
+	token, err := v.verificationDAO.Create(user.ID, EmailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/users/verify?token=%s", v.apiBaseURL, url.QueryEscape(token))
+	return v.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Bitte bestätige deine E-Mail-Adresse",
+		Body: fmt.Sprintf("Hallo %s,\n\nbitte bestätige deine E-Mail-Adresse über folgenden Link:\n\n%s\n\n"+
+			"Der Link ist %d Stunden gültig. Bis zur Bestätigung kannst du deine Daten nur ansehen.\n",
+			user.Name, link, int(EmailVerificationTokenTTL.Hours())),
+	})
+}
+
+// sendAsync mails the verification link in the background and only logs failures.
+func (v *EmailVerifier) sendAsync(user models.User) {
+	go func() {
+		if err := v.Send(&user); err != nil {
+			log.Printf("[EmailVerifier] ERROR sending verification mail to user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+func RegisterVerificationRoutes(r *gin.Engine, userDAO *dao.UserDAO, verificationDAO *dao.EmailVerificationDAO, verifier *EmailVerifier, requireAuth gin.HandlerFunc) {
+	r.GET("/users/verify", verifyEmail(verificationDAO))
+	r.POST("/users/verify/resend", requireAuth, resendVerification(userDAO, verifier))
+}
+
+func verifyEmail(verificationDAO *dao.EmailVerificationDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing token"})
+			return
+		}
+
+		_, err := verificationDAO.Verify(token)
+		if errors.Is(err, dao.ErrVerificationTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+	}
+}
+
+func resendVerification(userDAO *dao.UserDAO, verifier *EmailVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userDAO.GetByID(middleware.UserID(c))
+		if err != nil || user == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+		if user.IsVerified() {
+			c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+			return
+		}
+
+		if err := verifier.Send(user); err != nil {
+			log.Printf("[EmailVerifier] ERROR sending verification mail to user %d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification mail"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Verification mail sent"})
+	}
+}
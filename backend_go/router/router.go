@@ -1,10 +1,18 @@
 package router
 
 import (
+	"backend_go/auth"
+	"backend_go/blobstore"
 	"backend_go/db"
 	"backend_go/db/dao"
+	"backend_go/mail"
+	"backend_go/oidc"
+	"backend_go/router/middleware"
 	"backend_go/router/rest"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,12 +22,17 @@ import (
 func SetupRouter() *gin.Engine {
 	r := gin.Default()
 	r.RedirectTrailingSlash = false
+	// Only listed proxies may set X-Forwarded-For; otherwise ClientIP (login
+	// throttling, audit log) would be whatever the client claims
+	if err := r.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 	// ✅ Explicitly define CORS rules
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:4200"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour, // Cache pre-flight OPTIONS request
 	}))
@@ -27,8 +40,8 @@ func SetupRouter() *gin.Engine {
 	// ✅ Handle OPTIONS preflight requests
 	r.OPTIONS("/*path", func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:4200")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, If-Match")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Status(204) // No Content
 	})
@@ -45,9 +58,111 @@ func SetupRouter() *gin.Engine {
 		log.Fatal("Error connecting to the database: ", err)
 	}
 
+	// Bring the schema up to date; DB_AUTO_MIGRATE=false leaves it to `backend_go migrate up`
+	if os.Getenv("DB_AUTO_MIGRATE") != "false" {
+		if err := db.Migrate(database); err != nil {
+			log.Fatal("Error migrating the database: ", err)
+		}
+	}
+
+	// Access tokens are signed with JWT_SECRET
+	tokens, err := auth.NewTokenManagerFromEnv()
+	if err != nil {
+		log.Fatal("Error configuring access tokens: ", err)
+	}
+
+	// TOTP secrets are encrypted at rest with TOTP_ENCRYPTION_KEY (base64, 32 bytes)
+	secretBox, err := auth.NewSecretBoxFromBase64(os.Getenv("TOTP_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatal("Error configuring TOTP_ENCRYPTION_KEY: ", err)
+	}
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Leviathan"
+	}
+
+	// Outgoing mail (password reset links etc.)
+	mailer, err := mail.NewMailerFromEnv()
+	if err != nil {
+		log.Fatal("Error configuring the mailer: ", err)
+	}
+
+	// Links in mails point to the frontend
+	appBaseURL := os.Getenv("APP_BASE_URL")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:4200"
+	}
+	apiBaseURL := os.Getenv("API_BASE_URL")
+	if apiBaseURL == "" {
+		apiBaseURL = "http://localhost:8000"
+	}
+
+	// Deleted accounts are kept for a grace period (Go duration, "0" deletes immediately)
+	deletionGrace := 30 * 24 * time.Hour
+	if v := os.Getenv("ACCOUNT_DELETION_GRACE"); v != "" {
+		deletionGrace, err = time.ParseDuration(v)
+		if err != nil || deletionGrace < 0 {
+			log.Fatal("Invalid ACCOUNT_DELETION_GRACE: ", v)
+		}
+	}
+
+	// Upper limit for uploaded receipts in bytes
+	maxReceiptSize := rest.DefaultMaxReceiptSize
+	if v := os.Getenv("RECEIPT_MAX_BYTES"); v != "" {
+		maxReceiptSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || maxReceiptSize <= 0 {
+			log.Fatal("Invalid RECEIPT_MAX_BYTES: ", v)
+		}
+	}
+
+	// External login providers (OIDC_PROVIDERS, see oidc.ProvidersFromEnv)
+	oidcProviders, err := oidc.ProvidersFromEnv(apiBaseURL)
+	if err != nil {
+		log.Fatal("Error configuring OIDC providers: ", err)
+	}
+
+	// Where receipts are stored (RECEIPT_STORE, see blobstore.FromEnv)
+	receiptStore, err := blobstore.FromEnv(database)
+	if err != nil {
+		log.Fatal("Error configuring the receipt store: ", err)
+	}
+
 	// Initialize DAOs
-	userDAO := dao.NewUserDAO(database)
-	expenseDAO := dao.NewHaushaltsausgabenDAO(database)
+	userDAO := dao.NewUserDAO(database, receiptStore)
+	expenseDAO := dao.NewHaushaltsausgabenDAO(database, auth.OwnerOnlyPolicy{}, receiptStore)
+	sessionDAO := dao.NewRefreshTokenDAO(database)
+	resetDAO := dao.NewPasswordResetDAO(database)
+	verificationDAO := dao.NewEmailVerificationDAO(database)
+	twoFactorDAO := dao.NewTwoFactorDAO(database, secretBox)
+	throttleDAO := dao.NewLoginThrottleDAO(database)
+	apiTokenDAO := dao.NewAPITokenDAO(database)
+	auditDAO := dao.NewAuditDAO(database)
+	oidcDAO := dao.NewOIDCDAO(database)
+	categoryDAO := dao.NewCategoryDAO(database)
+	budgetDAO := dao.NewBudgetDAO(database, expenseDAO)
+	audit := rest.NewAuditLog(auditDAO)
+	verifier := rest.NewEmailVerifier(verificationDAO, mailer, apiBaseURL)
+
+	// The first admin comes from the configuration (ADMIN_EMAIL, optionally ADMIN_PASSWORD)
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		admin, err := userDAO.EnsureAdmin(adminEmail, os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			log.Fatal("Error bootstrapping the admin account: ", err)
+		}
+		if admin == nil {
+			log.Printf("ADMIN_EMAIL %s is not a verified account yet, verify it or set ADMIN_PASSWORD to create it", adminEmail)
+		}
+	}
+
+	// Shared authentication middleware for all protected routes (login sessions only)
+	requireAuth := middleware.RequireAuth(tokens, sessionDAO, nil)
+	// Expense routes additionally accept personal API tokens (checked per route by scope)
+	requireAPIAuth := middleware.RequireAuth(tokens, sessionDAO, apiTokenDAO)
+	requireVerified := middleware.RequireVerified(userDAO)
+	requireAdmin := middleware.RequireRole(auth.RoleAdmin)
+
+	// Accounts whose deletion grace period ended are purged in the background
+	go purgeScheduledDeletions(userDAO, auditDAO, time.Hour)
 
 	// General route to test if server is running
 	r.GET("/ping", func(c *gin.Context) {
@@ -55,8 +170,50 @@ func SetupRouter() *gin.Engine {
 	})
 
 	// Register routes
-	rest.RegisterUserRoutes(r, userDAO)
-	rest.RegisterHaushaltsausgabenRoutes(r, expenseDAO)
+	rest.RegisterUserRoutes(r, userDAO, sessionDAO, twoFactorDAO, throttleDAO, tokens, verifier, audit, requireAuth, requireAdmin)
+	rest.RegisterTwoFactorRoutes(r, userDAO, twoFactorDAO, sessionDAO, throttleDAO, tokens, audit, totpIssuer, requireAuth)
+	rest.RegisterVerificationRoutes(r, userDAO, verificationDAO, verifier, requireAuth)
+	rest.RegisterPasswordResetRoutes(r, userDAO, resetDAO, sessionDAO, throttleDAO, mailer, audit, appBaseURL)
+	rest.RegisterHaushaltsausgabenRoutes(r, expenseDAO, requireAPIAuth, requireVerified, maxReceiptSize)
+	rest.RegisterCategoryRoutes(r, categoryDAO, requireAPIAuth, requireVerified)
+	rest.RegisterBudgetRoutes(r, budgetDAO, categoryDAO, requireAPIAuth, requireVerified)
+	rest.RegisterSummaryRoutes(r, userDAO, expenseDAO, requireAPIAuth, requireVerified)
+	rest.RegisterAccountRoutes(r, userDAO, expenseDAO, sessionDAO, apiTokenDAO, audit, deletionGrace, requireAuth)
+	rest.RegisterAPITokenRoutes(r, apiTokenDAO, audit, requireAuth)
+	rest.RegisterJWKSRoutes(r, tokens)
+	rest.RegisterOIDCRoutes(r, oidcProviders, userDAO, oidcDAO, twoFactorDAO, sessionDAO, tokens, audit, appBaseURL)
+	rest.RegisterAdminRoutes(r, userDAO, sessionDAO, throttleDAO, auditDAO, audit, requireAuth, requireAdmin)
 
 	return r
 }
+
+// trustedProxiesFromEnv reads TRUSTED_PROXIES, a comma separated list of IPs or
+// CIDRs of reverse proxies. Unset means no proxy is trusted.
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// purgeScheduledDeletions deletes accounts whose deletion grace period has ended,
+// once at startup and then every interval.
+func purgeScheduledDeletions(userDAO *dao.UserDAO, auditDAO *dao.AuditDAO, interval time.Duration) {
+	for {
+		purged, err := userDAO.PurgeScheduledDeletions(time.Now())
+		if err != nil {
+			log.Printf("Failed to purge deleted accounts: %v", err)
+		} else if len(purged) > 0 {
+			log.Printf("Purged %d deleted accounts", len(purged))
+		}
+		for _, id := range purged {
+			if err := auditDAO.RecordAccountPurged(id); err != nil {
+				log.Printf("[Audit] ERROR recording purge of user %d: %v", id, err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
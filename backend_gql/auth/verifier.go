@@ -0,0 +1,211 @@
+// auth/verifier.go
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenIssuer muss mit dem iss-Claim der Tokens von backend_go übereinstimmen.
+const tokenIssuer = "backend_go"
+
+// jwksRefreshInterval begrenzt, wie oft die Schlüssel bei unbekannter kid neu geladen werden.
+const jwksRefreshInterval = time.Minute
+
+// ErrInvalidToken wird für ungültige, abgelaufene oder fremde Tokens zurückgegeben.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims sind die Claims eines Access-Tokens von backend_go. Die Postgres-User-ID
+// steht im Subject.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	Type      string `json:"typ"`
+	Role      string `json:"role,omitempty"`
+}
+
+// UserID gibt die Postgres-User-ID aus dem Subject zurück.
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// Verifier prüft Access-Tokens von backend_go, entweder mit den öffentlichen
+// Schlüsseln aus dessen JWKS oder mit einem gemeinsamen HS256-Secret.
+type Verifier struct {
+	secret  []byte
+	jwksURL string
+	client  *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]ed25519.PublicKey
+	lastFetched time.Time
+	fetching    chan struct{} // offen, solange die JWKS geladen werden
+}
+
+// NewVerifierFromEnv liest JWKS_URL (z.B. http://backend_go:8000/.well-known/jwks.json)
+// oder, falls nicht gesetzt, JWT_SECRET.
+func NewVerifierFromEnv() (*Verifier, error) {
+	if url := os.Getenv("JWKS_URL"); url != "" {
+		return NewJWKSVerifier(url), nil
+	}
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) < 32 {
+		return nil, errors.New("JWKS_URL oder JWT_SECRET (mindestens 32 Zeichen) muss gesetzt sein")
+	}
+	return NewSecretVerifier([]byte(secret)), nil
+}
+
+// NewJWKSVerifier erstellt einen Verifier, der die Schlüssel von url lädt.
+func NewJWKSVerifier(url string) *Verifier {
+	return &Verifier{jwksURL: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// NewSecretVerifier erstellt einen Verifier für HS256-Tokens.
+func NewSecretVerifier(secret []byte) *Verifier {
+	return &Verifier{secret: secret}
+}
+
+// Verify prüft Signatur, Aussteller, Ablauf und Typ eines Access-Tokens.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	method := jwt.SigningMethodHS256.Alg()
+	if v.jwksURL != "" {
+		method = jwt.SigningMethodEdDSA.Alg()
+	}
+
+	claims := &Claims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, v.keyFunc,
+		jwt.WithValidMethods([]string{method}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	// MFA-Zwischentokens dürfen nicht als Login durchgehen
+	if err != nil || !parsed.Valid || claims.Type != "access" {
+		return nil, ErrInvalidToken
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (v *Verifier) keyFunc(t *jwt.Token) (interface{}, error) {
+	if v.jwksURL == "" {
+		return v.secret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	return v.publicKey(kid)
+}
+
+// publicKey liefert den Schlüssel zur kid. Unbekannte kids lösen ein erneutes
+// Laden der JWKS aus, damit Schlüsselwechsel ohne Neustart funktionieren. Während
+// des Ladens ist mu frei, damit bekannte kids nicht auf den HTTP-Request warten;
+// weitere unbekannte kids warten auf das laufende Laden statt selbst zu laden.
+func (v *Verifier) publicKey(kid string) (ed25519.PublicKey, error) {
+	v.mu.Lock()
+	if key, ok := v.keys[kid]; ok {
+		v.mu.Unlock()
+		return key, nil
+	}
+	if done := v.fetching; done != nil {
+		v.mu.Unlock()
+		<-done
+		return v.cachedKey(kid)
+	}
+	if time.Since(v.lastFetched) < jwksRefreshInterval {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("unbekannte kid %q", kid)
+	}
+	done := make(chan struct{})
+	v.fetching = done
+	v.lastFetched = time.Now()
+	v.mu.Unlock()
+
+	keys, err := v.fetchKeys()
+
+	v.mu.Lock()
+	if err == nil {
+		v.keys = keys
+	}
+	v.fetching = nil
+	close(done)
+	v.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return v.cachedKey(kid)
+}
+
+func (v *Verifier) cachedKey(kid string) (ed25519.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unbekannte kid %q", kid)
+}
+
+func (v *Verifier) fetchKeys() (map[string]ed25519.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS konnte nicht geladen werden: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS konnte nicht geladen werden: Status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("JWKS ungültig: %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	return keys, nil
+}
+
+type contextKey struct{}
+
+// WithClaims hängt die Claims des angemeldeten Nutzers an ctx an.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext liefert die Claims des angemeldeten Nutzers, z.B. in Resolvern.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves the public keys it currently holds, like backend_go's
+// /.well-known/jwks.json, and counts how often it was asked.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]ed25519.PublicKey
+	requests int
+	block    chan struct{} // if set, requests wait until it is closed
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: map[string]ed25519.PublicKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		block := s.block
+		set := []map[string]string{}
+		for kid, key := range s.keys {
+			set = append(set, map[string]string{
+				"kty": "OKP", "crv": "Ed25519", "alg": "EdDSA", "use": "sig", "kid": kid,
+				"x": base64.RawURLEncoding.EncodeToString(key),
+			})
+		}
+		s.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keys[kid] = pub
+	s.mu.Unlock()
+	return priv
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func accessClaims(expires time.Time) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   "42",
+			IssuedAt:  jwt.NewNumericDate(expires.Add(-15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		Type: "access",
+	}
+}
+
+func signEdDSA(t *testing.T, key ed25519.PrivateKey, kid string, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func signHS256(t *testing.T, secret []byte, claims Claims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.addKey(t, "k1")
+	v := NewJWKSVerifier(srv.URL)
+
+	claims, err := v.Verify(signEdDSA(t, key, "k1", accessClaims(time.Now().Add(time.Minute))))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if id, _ := claims.UserID(); id != 42 {
+		t.Fatalf("UserID = %d, want 42", id)
+	}
+}
+
+func TestVerifyJWKSKeyRotation(t *testing.T) {
+	srv := newJWKSServer(t)
+	oldKey := srv.addKey(t, "k1")
+	v := NewJWKSVerifier(srv.URL)
+	if _, err := v.Verify(signEdDSA(t, oldKey, "k1", accessClaims(time.Now().Add(time.Minute)))); err != nil {
+		t.Fatalf("Verify old key: %v", err)
+	}
+
+	newKey := srv.addKey(t, "k2")
+	rotated := signEdDSA(t, newKey, "k2", accessClaims(time.Now().Add(time.Minute)))
+
+	// Within the refresh interval an unknown kid does not trigger another request
+	if _, err := v.Verify(rotated); err == nil {
+		t.Fatal("unknown kid accepted before refresh")
+	}
+	if n := srv.requestCount(); n != 1 {
+		t.Fatalf("JWKS requests = %d, want 1", n)
+	}
+
+	v.mu.Lock()
+	v.lastFetched = time.Now().Add(-jwksRefreshInterval)
+	v.mu.Unlock()
+
+	if _, err := v.Verify(rotated); err != nil {
+		t.Fatalf("Verify rotated key: %v", err)
+	}
+	if _, err := v.Verify(signEdDSA(t, oldKey, "k1", accessClaims(time.Now().Add(time.Minute)))); err != nil {
+		t.Fatalf("old key still published but rejected: %v", err)
+	}
+	if n := srv.requestCount(); n != 2 {
+		t.Fatalf("JWKS requests = %d, want 2", n)
+	}
+}
+
+func TestVerifyKnownKidDoesNotWaitForFetch(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.addKey(t, "k1")
+	v := NewJWKSVerifier(srv.URL)
+	valid := signEdDSA(t, key, "k1", accessClaims(time.Now().Add(time.Minute)))
+	if _, err := v.Verify(valid); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	block := make(chan struct{})
+	srv.mu.Lock()
+	srv.block = block
+	srv.mu.Unlock()
+	v.mu.Lock()
+	v.lastFetched = time.Time{}
+	v.mu.Unlock()
+
+	other := srv.addKey(t, "k2")
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(signEdDSA(t, other, "k2", accessClaims(time.Now().Add(time.Minute))))
+		fetched <- err
+	}()
+	for srv.requestCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(valid)
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Fatalf("Verify during fetch: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("known kid blocked by a running JWKS fetch")
+	}
+
+	close(block)
+	if err := <-fetched; err != nil {
+		t.Fatalf("Verify after fetch: %v", err)
+	}
+}
+
+func TestVerifyRejectsWrongAlgorithm(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.addKey(t, "k1")
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	claims := accessClaims(time.Now().Add(time.Minute))
+
+	if _, err := NewJWKSVerifier(srv.URL).Verify(signHS256(t, secret, claims)); err == nil {
+		t.Fatal("JWKS verifier accepted an HS256 token")
+	}
+	if _, err := NewSecretVerifier(secret).Verify(signEdDSA(t, key, "k1", claims)); err == nil {
+		t.Fatal("secret verifier accepted an EdDSA token")
+	}
+	if _, err := NewSecretVerifier(secret).Verify(signHS256(t, secret, claims)); err != nil {
+		t.Fatalf("secret verifier: %v", err)
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSecretVerifier(secret).Verify(none); err == nil {
+		t.Fatal("unsigned token accepted")
+	}
+}
+
+func TestVerifyRejectsInvalidClaims(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	v := NewSecretVerifier(secret)
+	now := time.Now()
+
+	expired := accessClaims(now.Add(-time.Minute))
+	noExpiry := accessClaims(now)
+	noExpiry.ExpiresAt = nil
+	mfa := accessClaims(now.Add(time.Minute))
+	mfa.Type = "mfa"
+	foreign := accessClaims(now.Add(time.Minute))
+	foreign.Issuer = "someone-else"
+	badSubject := accessClaims(now.Add(time.Minute))
+	badSubject.Subject = "admin"
+
+	cases := map[string]string{
+		"expired":     signHS256(t, secret, expired),
+		"no expiry":   signHS256(t, secret, noExpiry),
+		"mfa token":   signHS256(t, secret, mfa),
+		"issuer":      signHS256(t, secret, foreign),
+		"subject":     signHS256(t, secret, badSubject),
+		"wrong key":   signHS256(t, []byte("fedcba9876543210fedcba9876543210"), accessClaims(now.Add(time.Minute))),
+		"not a token": "abc.def.ghi",
+	}
+	for name, token := range cases {
+		if _, err := v.Verify(token); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
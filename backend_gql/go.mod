@@ -4,6 +4,7 @@ go 1.24
 
 require (
 	github.com/gin-gonic/gin v1.10.0 // Version anpassen
+	github.com/golang-jwt/jwt/v5 v5.2.2 // Version wie in backend_go
 	github.com/graphql-go/graphql v0.8.1 // Version wie im anderen Backend
 	go.mongodb.org/mongo-driver v1.15.1 // Version anpassen
 )
@@ -36,7 +37,7 @@ require (
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
 	golang.org/x/arch v0.15.0 // indirect
-	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/crypto v0.36.0
 	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/sync v0.12.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
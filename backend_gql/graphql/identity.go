@@ -0,0 +1,176 @@
+// graphql/identity.go
+package graphql
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_gql/auth"
+
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errNotAuthenticated wird zurückgegeben, wenn der Request kein gültiges Token hatte.
+var errNotAuthenticated = errors.New("nicht angemeldet")
+
+// errTooManyAttempts wird zurückgegeben, solange die Passwortbestätigung gesperrt ist.
+var errTooManyAttempts = errors.New("zu viele Fehlversuche, bitte später erneut versuchen")
+
+// ensureUserIndexes legt den eindeutigen Index auf pg_user_id an. Fehler werden
+// nur geloggt, damit der Dienst auch ohne Index-Rechte startet.
+func ensureUserIndexes(usersCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "pg_user_id", Value: 1}},
+		Options: options.Index().
+			SetName("pg_user_id_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.D{{Key: "pg_user_id", Value: bson.D{{Key: "$exists", Value: true}}}}),
+	})
+	if err != nil {
+		log.Printf("Index auf pg_user_id konnte nicht angelegt werden: %v", err)
+	}
+}
+
+// currentUserID liefert die Postgres-User-ID des angemeldeten Nutzers.
+func currentUserID(ctx context.Context) (int, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return 0, errNotAuthenticated
+	}
+	return claims.UserID()
+}
+
+// meField liefert das Dokument des angemeldeten Nutzers. Nutzer aus backend_go,
+// die noch kein Dokument haben, bekommen beim ersten Aufruf eines angelegt.
+func meField(usersCollection *mongo.Collection) *graphql.Field {
+	return &graphql.Field{
+		Type:        UserType,
+		Description: "Der angemeldete Benutzer",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			userID, err := currentUserID(p.Context)
+			if err != nil {
+				return nil, err
+			}
+
+			queryCtx, queryCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer queryCancel()
+
+			var user User
+			err = usersCollection.FindOneAndUpdate(queryCtx,
+				bson.D{{Key: "pg_user_id", Value: userID}},
+				bson.D{{Key: "$setOnInsert", Value: bson.D{
+					{Key: "pg_user_id", Value: userID},
+					{Key: "auto_provisioned", Value: true},
+				}}},
+				options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+			).Decode(&user)
+			if err != nil {
+				log.Printf("Fehler beim Laden des Benutzers %d: %v", userID, err)
+				return nil, err
+			}
+			return user, nil
+		},
+	}
+}
+
+// linkLegacyUserField verknüpft ein bestehendes Dokument, das noch ein eigenes
+// Passwort hat, mit dem angemeldeten Konto aus backend_go. Das alte Passwort
+// muss einmalig bestätigt werden und wird danach gelöscht. Fehlversuche werden je
+// Konto und je altem Benutzernamen begrenzt, sonst ließe sich das Passwort raten.
+func linkLegacyUserField(usersCollection *mongo.Collection) *graphql.Field {
+	limiter := newAttemptLimiter(legacyMaxFailures, legacyFailureWindow)
+	return &graphql.Field{
+		Type:        UserType,
+		Description: "Verknüpft einen bestehenden Rezeptbuch-Benutzer mit dem angemeldeten Konto",
+		Args: graphql.FieldConfigArgument{
+			"name":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			userID, err := currentUserID(p.Context)
+			if err != nil {
+				return nil, err
+			}
+			name, _ := p.Args["name"].(string)
+			password, _ := p.Args["password"].(string)
+
+			attemptKeys := []string{"user:" + strconv.Itoa(userID), "legacy:" + strings.ToLower(name)}
+			if !limiter.Allowed(attemptKeys...) {
+				return nil, errTooManyAttempts
+			}
+
+			queryCtx, queryCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer queryCancel()
+
+			// Ein Konto kann nur ein Dokument haben; ein automatisch angelegtes
+			// (leeres) Dokument wird durch das alte ersetzt
+			var existing User
+			err = usersCollection.FindOne(queryCtx, bson.D{{Key: "pg_user_id", Value: userID}}).Decode(&existing)
+			hasExisting := err == nil
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+			if hasExisting && !existing.AutoProvisioned {
+				return nil, errors.New("dieses Konto ist bereits verknüpft")
+			}
+
+			var legacy User
+			err = usersCollection.FindOne(queryCtx, bson.D{
+				{Key: "username", Value: name},
+				{Key: "pg_user_id", Value: bson.D{{Key: "$exists", Value: false}}},
+			}).Decode(&legacy)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+			if err != nil || !checkLegacyPassword(legacy.Password, password) {
+				limiter.Fail(attemptKeys...)
+				return nil, errors.New("Benutzername oder Passwort falsch")
+			}
+			limiter.Reset(attemptKeys...)
+
+			if hasExisting {
+				if _, err := usersCollection.DeleteOne(queryCtx, bson.D{{Key: "_id", Value: existing.ID}}); err != nil {
+					return nil, err
+				}
+			}
+
+			err = usersCollection.FindOneAndUpdate(queryCtx,
+				bson.D{{Key: "_id", Value: legacy.ID}},
+				bson.D{
+					{Key: "$set", Value: bson.D{{Key: "pg_user_id", Value: userID}}},
+					{Key: "$unset", Value: bson.D{{Key: "password", Value: ""}, {Key: "auto_provisioned", Value: ""}}},
+				},
+				options.FindOneAndUpdate().SetReturnDocument(options.After),
+			).Decode(&legacy)
+			if err != nil {
+				log.Printf("Fehler beim Verknüpfen von %s mit Benutzer %d: %v", name, userID, err)
+				return nil, err
+			}
+			return legacy, nil
+		},
+	}
+}
+
+// checkLegacyPassword vergleicht mit dem alten Passwort, das als bcrypt-Hash
+// oder noch im Klartext gespeichert sein kann.
+func checkLegacyPassword(stored, password string) bool {
+	if stored == "" {
+		return false
+	}
+	if strings.HasPrefix(stored, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
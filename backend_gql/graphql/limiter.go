@@ -0,0 +1,80 @@
+// graphql/limiter.go
+package graphql
+
+import (
+	"sync"
+	"time"
+)
+
+// Grenzen für das Bestätigen alter Rezeptbuch-Passwörter: nach legacyMaxFailures
+// Fehlversuchen innerhalb von legacyFailureWindow ist der Schlüssel gesperrt,
+// bis das Fenster abgelaufen ist.
+const (
+	legacyMaxFailures   = 5
+	legacyFailureWindow = 15 * time.Minute
+)
+
+// attemptLimiter zählt Fehlversuche je Schlüssel im Speicher. Das reicht für eine
+// Instanz von backend_gql; bei mehreren Instanzen gilt die Grenze je Instanz.
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]failureCount
+}
+
+type failureCount struct {
+	count int
+	first time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{max: max, window: window, now: time.Now, failures: map[string]failureCount{}}
+}
+
+// Allowed meldet, ob für alle Schlüssel noch Versuche übrig sind.
+func (l *attemptLimiter) Allowed(keys ...string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if f, ok := l.current(key); ok && f.count >= l.max {
+			return false
+		}
+	}
+	return true
+}
+
+// Fail zählt einen Fehlversuch für alle Schlüssel.
+func (l *attemptLimiter) Fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		f, ok := l.current(key)
+		if !ok {
+			f = failureCount{first: l.now()}
+		}
+		f.count++
+		l.failures[key] = f
+	}
+}
+
+// Reset vergisst die Fehlversuche nach einem erfolgreichen Versuch.
+func (l *attemptLimiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		delete(l.failures, key)
+	}
+}
+
+// current liefert den Zähler im laufenden Fenster; abgelaufene werden entfernt.
+func (l *attemptLimiter) current(key string) (failureCount, bool) {
+	f, ok := l.failures[key]
+	if ok && l.now().Sub(f.first) >= l.window {
+		delete(l.failures, key)
+		return failureCount{}, false
+	}
+	return f, ok
+}
@@ -0,0 +1,43 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newAttemptLimiter(3, 15*time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !l.Allowed("user:1", "legacy:max") {
+			t.Fatalf("attempt %d refused", i+1)
+		}
+		l.Fail("user:1", "legacy:max")
+	}
+	if l.Allowed("user:1") || l.Allowed("legacy:max") {
+		t.Fatal("attempts allowed after the limit")
+	}
+	// Another account guessing the same legacy user is refused as well
+	if l.Allowed("user:2", "legacy:max") {
+		t.Fatal("limit per legacy user not applied")
+	}
+	if !l.Allowed("user:2", "legacy:other") {
+		t.Fatal("unrelated keys must not be limited")
+	}
+
+	now = now.Add(15 * time.Minute)
+	if !l.Allowed("user:1", "legacy:max") {
+		t.Fatal("limit must end with the window")
+	}
+
+	l.Fail("user:1")
+	l.Fail("user:1")
+	l.Reset("user:1")
+	l.Fail("user:1")
+	l.Fail("user:1")
+	if !l.Allowed("user:1") {
+		t.Fatal("Reset must clear earlier failures")
+	}
+}
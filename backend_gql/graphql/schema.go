@@ -37,6 +37,10 @@ type User struct {
 	Size     int    `bson:"size,omitempty"`      // bson:"size" in DB
 	EyeColor string `bson:"eye_color,omitempty"` // bson:"eye_color" in DB
 
+	// Verknüpfung mit dem Konto in backend_go (Postgres users.id). Angemeldet wird
+	// nur noch dort; das alte 'password'-Feld wird beim Verknüpfen entfernt.
+	PgUserID        *int `bson:"pg_user_id,omitempty"`
+	AutoProvisioned bool `bson:"auto_provisioned,omitempty"` // beim ersten Login automatisch angelegt
 }
 
 // Nutrition struct (typisch für Zutaten)
@@ -101,6 +105,17 @@ var UserType = graphql.NewObject(graphql.ObjectConfig{
 		"weight":   &graphql.Field{Type: graphql.String}, // <-- Hinzugefügt
 		"height":   &graphql.Field{Type: graphql.String}, // <-- Hinzugefügt
 
+		// ID des verknüpften Kontos in backend_go
+		"pgUserId": &graphql.Field{
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if user, ok := p.Source.(User); ok && user.PgUserID != nil {
+					return *user.PgUserID, nil
+				}
+				return nil, nil
+			},
+		},
+
 		// Das 'password'-Feld wird NICHT hier hinzugefügt/exportiert.
 	},
 })
@@ -139,6 +154,9 @@ func BuildSchema(mongoDatabase *mongo.Database) (graphql.Schema, error) {
 	}
 	recipesCollection := mongoDatabase.Collection(recipesCollectionName)
 
+	// Jedes Konto aus backend_go darf nur mit einem Dokument verknüpft sein
+	ensureUserIndexes(usersCollection)
+
 	// Definition des Root Query Typs
 	rootQuery := graphql.NewObject(graphql.ObjectConfig{
 		Name: "RootQuery", // Konventioneller Name
@@ -271,14 +289,24 @@ func BuildSchema(mongoDatabase *mongo.Database) (graphql.Schema, error) {
 					return recipes, nil
 				},
 			},
+			// === Angemeldeter Benutzer ===
+			"me": meField(usersCollection),
 			// Fügen Sie hier weitere Query-Felder hinzu (z.B. Mutationen in einem separaten Typ)
 		},
 	})
 
+	// Definition des Root Mutation Typs
+	rootMutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootMutation",
+		Fields: graphql.Fields{
+			"linkLegacyUser": linkLegacyUserField(usersCollection),
+		},
+	})
+
 	// Erstellen Sie das finale GraphQL Schema.
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query: rootQuery,
-		// Mutation: rootMutation, // Fügen Sie hier Mutationen hinzu, falls vorhanden
+		Query:    rootQuery,
+		Mutation: rootMutation,
 		// Subscription: rootSubscription, // Fügen Sie hier Subscriptions hinzu, falls vorhanden
 	})
 	if err != nil {
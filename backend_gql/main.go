@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"backend_gql/auth"
 	"backend_gql/database"
 	"backend_gql/router"
 )
@@ -30,9 +31,15 @@ func main() {
 	// Wichtig: Client am Ende schließen!
 	defer database.CloseDBClient(mongoClient) // Einfaches Defer hier für den Hauptclient
 
+	// --- Tokens von backend_go prüfen (JWKS_URL oder JWT_SECRET) ---
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Fehler bei der Token-Konfiguration: %v", err)
+	}
+
 	// --- Router initialisieren ---
 	// Übergib die DB-Instanz an den spezifischen GraphQL-Router Setup
-	engine := router.SetupGraphQLRouter(mongoDatabase)
+	engine := router.SetupGraphQLRouter(mongoDatabase, verifier)
 	log.Println("GraphQL Router erfolgreich eingerichtet.")
 
 	// --- HTTP Server starten ---
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"backend_gql/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAuth akzeptiert nur Anfragen mit gültigem Access-Token von backend_go
+// und legt die Claims im Request-Kontext ab, wo die GraphQL-Resolver sie finden.
+func RequireAuth(verifier *auth.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="backend_gql"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Fehlender oder ungültiger Authorization-Header"})
+			return
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="backend_gql", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Ungültiges oder abgelaufenes Token"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithClaims(c.Request.Context(), claims))
+		c.Next()
+	}
+}
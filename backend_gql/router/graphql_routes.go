@@ -10,8 +10,8 @@ import (
 )
 
 // RegisterGraphQLRoutes sets up the GraphQL endpoint and GraphiQL IDE route on the provided Gin router.
-// It requires a compiled GraphQL schema.
-func RegisterGraphQLRoutes(r *gin.Engine, schema gql.Schema) {
+// It requires a compiled GraphQL schema; requireAuth guards the endpoint.
+func RegisterGraphQLRoutes(r *gin.Engine, schema gql.Schema, requireAuth gin.HandlerFunc) {
 	// --- GraphQL HTTP Handler ---
 	graphqlHandler := func(c *gin.Context) {
 		// Set Content-Type header to JSON
@@ -54,7 +54,7 @@ func RegisterGraphQLRoutes(r *gin.Engine, schema gql.Schema) {
 	}
 
 	// Register routes
-	r.POST("/graphql", graphqlHandler)
+	r.POST("/graphql", requireAuth, graphqlHandler)
 
 	log.Println("GraphQL endpoint registriert unter /graphql (POST)")
 	log.Println("GraphiQL IDE verfügbar unter /graphiql (GET)")
@@ -4,6 +4,7 @@ import (
 	"log"
 	"time"
 
+	"backend_gql/auth"
 	"backend_gql/graphql" // Passe Pfad an, falls Modulname anders
 
 	"github.com/gin-contrib/cors"
@@ -12,7 +13,8 @@ import (
 )
 
 // SetupGraphQLRouter konfiguriert den Gin-Router NUR für GraphQL Endpunkte.
-func SetupGraphQLRouter(mongoDatabase *mongo.Database) *gin.Engine {
+// Angemeldet wird über backend_go; verifier prüft dessen Access-Tokens.
+func SetupGraphQLRouter(mongoDatabase *mongo.Database, verifier *auth.Verifier) *gin.Engine {
 	r := gin.Default()
 	r.RedirectTrailingSlash = false
 
@@ -38,7 +40,7 @@ func SetupGraphQLRouter(mongoDatabase *mongo.Database) *gin.Engine {
 
 	// Registriere die GraphQL Endpunkte (/graphql)
 	// Die Funktion RegisterGraphQLRoutes ist in router/graphql_routes.go definiert
-	RegisterGraphQLRoutes(r, schema)
+	RegisterGraphQLRoutes(r, schema, RequireAuth(verifier))
 
 	log.Println("GraphQL Router Setup abgeschlossen.")
 	return r
@@ -2,6 +2,50 @@ import { createApp } from 'vue';
 import App from './App.vue';
 import { createPinia } from 'pinia'
 import router from './router';
+import axios from 'axios';
+import { useUserStore } from './stores/userStore';
+
+// Access Token aus dem Login an jede Backend-Anfrage anhängen
+axios.interceptors.request.use((config) => {
+  const token = localStorage.getItem('accessToken');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
+// Endpunkte, bei denen ein 401 kein abgelaufenes Access Token bedeutet
+const authEndpoints = [
+  '/users/login',
+  '/users/login/2fa',
+  '/users/authenticate',
+  '/users/refresh',
+  '/users/logout',
+  '/users/password-reset',
+  '/auth/oidc/exchange',
+];
+
+// Bei 401 einmal das Token-Paar über /users/refresh erneuern und die Anfrage
+// wiederholen; schlägt das fehl, ist die Sitzung beendet
+axios.interceptors.response.use(undefined, async (error) => {
+  const config = error.config;
+  const url: string = config?.url ?? '';
+  if (error.response?.status !== 401 || !config || config._retried || authEndpoints.some((path) => url.includes(path))) {
+    return Promise.reject(error);
+  }
+  config._retried = true;
+
+  const userStore = useUserStore();
+  try {
+    const accessToken = await userStore.refreshTokens();
+    config.headers.Authorization = `Bearer ${accessToken}`;
+  } catch {
+    userStore.logout();
+    router.push('/loginregister');
+    return Promise.reject(error);
+  }
+  return axios(config);
+});
 
 const app = createApp(App);
 
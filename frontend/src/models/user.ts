@@ -1,6 +1,7 @@
 export interface User {
     id?: number;
     name: string;
+    username?: string;
     email: string;
     password: string;
     income?: number;
@@ -179,7 +179,7 @@ class HouseholdExpensesService {
 
   async getExpensesByUserAndMonth(userid: number, month: string) {
      try {
-      const response = await axios.get(`${this.apiUrl}/haushaltsausgaben/${userid}/${month}`);
+      const response = await axios.get(`${this.apiUrl}/haushaltsausgaben/user/${userid}/${month}`);
       const expenses = response.data.map((expense: any) => {
         if (expense.CreditStart) expense.CreditStart = this.formatDate(expense.CreditStart);
         if (expense.CreditEnd) expense.CreditEnd = this.formatDate(expense.CreditEnd);
@@ -6,6 +6,17 @@ const API_URL = 'http://localhost:8000/users/';
 
 
 
+// Maps a login response to the user data and token pair
+function toSession(data: any, identifier: string) {
+  return {
+    id: data.user_id, // Map user_id to id
+    name: data.name,
+    email: identifier, // Since backend doesn't return email, use the input identifier
+    accessToken: data.access_token,
+    refreshToken: data.refresh_token,
+  };
+}
+
 class UserService {
   // Register a new user
   async register(user: User): Promise<any> {
@@ -17,17 +28,49 @@ class UserService {
     }
   }
 
-  // Authenticate a user and return user data
+  // Authenticate a user and return user data. Accounts with two-factor
+  // authentication only get an MFA token, which completeTwoFactorLogin exchanges
   async authenticate(identifier: string, password: string): Promise<any> {
     try {
       const response = await axios.post(`${API_URL}authenticate`, { identifier, password });
+      if (response.data.mfa_required) {
+        return { mfaRequired: true, mfaToken: response.data.mfa_token };
+      }
+      return toSession(response.data, identifier);
+    } catch (error) {
+      throw new Error(`Failed to authenticate user: ${error}`);
+    }
+  }
+
+  // Second login step: exchange the MFA token and a TOTP or recovery code for a session
+  async completeTwoFactorLogin(mfaToken: string, code: string, identifier: string): Promise<any> {
+    try {
+      const response = await axios.post(`${API_URL}login/2fa`, { mfa_token: mfaToken, code });
+      return toSession(response.data, identifier);
+    } catch (error) {
+      throw new Error(`Failed to verify code: ${error}`);
+    }
+  }
+
+  // Exchange the refresh token for a new token pair; the old refresh token becomes invalid
+  async refresh(refreshToken: string): Promise<{ accessToken: string; refreshToken: string }> {
+    try {
+      const response = await axios.post(`${API_URL}refresh`, { refresh_token: refreshToken });
       return {
-        id: response.data.user_id, // Map user_id to id
-        name: response.data.name,
-        email: identifier, // Since backend doesn't return email, use the input identifier
+        accessToken: response.data.access_token,
+        refreshToken: response.data.refresh_token,
       };
     } catch (error) {
-      throw new Error(`Failed to authenticate user: ${error}`);
+      throw new Error(`Failed to refresh session: ${error}`);
+    }
+  }
+
+  // Revoke the session belonging to the refresh token
+  async logout(refreshToken: string): Promise<void> {
+    try {
+      await axios.post(`${API_URL}logout`, { refresh_token: refreshToken });
+    } catch (error) {
+      throw new Error(`Failed to log out: ${error}`);
     }
   }
 
@@ -50,7 +93,7 @@ class UserService {
     }
   }
 
-  // Delete a user
+  // Delete a user (admins only, for other accounts)
   async deleteUser(id: number): Promise<void> {
     try {
       await axios.delete(`${API_URL}${id}`);
@@ -58,6 +101,16 @@ class UserService {
       throw new Error(`Failed to delete user: ${error}`);
     }
   }
+
+  // Delete the own account; it is removed after the grace period unless the deletion is cancelled
+  async deleteAccount(password: string): Promise<string> {
+    try {
+      const response = await axios.delete(`${API_URL}me`, { data: { password } });
+      return response.data.message;
+    } catch (error) {
+      throw new Error(`Failed to delete account: ${error}`);
+    }
+  }
   
   async getUserById(id: number): Promise<User> {
     try {
@@ -2,6 +2,10 @@ import { defineStore } from 'pinia';
 import { userService } from '../services/UserService.ts';
 import type { User } from '../models/user';
 
+// Refresh tokens are single use and presenting one twice revokes the session,
+// so concurrent 401s share the refresh that is already running
+let pendingRefresh: Promise<string> | null = null;
+
 // Define the state interface explicitly
 interface UserStoreState {
   user: User | null;
@@ -16,32 +20,78 @@ export const useUserStore = defineStore('user', {
   }),
 
   actions: {
-    async login(identifier: string, password: string) {
+    // Returns the MFA token if the account needs a second factor; the login is
+    // then finished with completeTwoFactorLogin
+    async login(identifier: string, password: string): Promise<string | null> {
       try {
         // Call the authentication service to check credentials
         const response = await userService.authenticate(identifier, password);
-
-        // Check if the user is found
-        if (response && response.id) {
-          // Store user info in the state
-          this.user = { id: response.id, name: response.name, email: response.email, password: response.password };
-          this.isAuthenticated = true;
-
-          // Store user info in localStorage
-          localStorage.setItem('user', JSON.stringify(this.user));
-        } else {
-          throw new Error('Invalid login credentials');
+        if (response?.mfaRequired) {
+          return response.mfaToken;
         }
+        this.startSession(response);
+        return null;
+      } catch (error: any) {
+        throw new Error(error.message || 'Login failed');
+      }
+    },
+
+    async completeTwoFactorLogin(identifier: string, mfaToken: string, code: string) {
+      try {
+        const response = await userService.completeTwoFactorLogin(mfaToken, code, identifier);
+        this.startSession(response);
       } catch (error: any) {
         throw new Error(error.message || 'Login failed');
       }
     },
 
+    startSession(response: any) {
+      // Check if the user is found
+      if (!response || !response.id) {
+        throw new Error('Invalid login credentials');
+      }
+      // Store user info in the state
+      this.user = { id: response.id, name: response.name, email: response.email, password: response.password };
+      this.isAuthenticated = true;
+
+      // Store user info and the access token in localStorage
+      localStorage.setItem('user', JSON.stringify(this.user));
+      localStorage.setItem('accessToken', response.accessToken);
+      localStorage.setItem('refreshToken', response.refreshToken);
+    },
+
+    // Rotate the token pair and return the new access token; throws if the session is gone
+    refreshTokens(): Promise<string> {
+      if (!pendingRefresh) {
+        pendingRefresh = (async () => {
+          const refreshToken = localStorage.getItem('refreshToken');
+          if (!refreshToken) {
+            throw new Error('No refresh token');
+          }
+          const tokens = await userService.refresh(refreshToken);
+          localStorage.setItem('accessToken', tokens.accessToken);
+          localStorage.setItem('refreshToken', tokens.refreshToken);
+          return tokens.accessToken;
+        })().finally(() => {
+          pendingRefresh = null;
+        });
+      }
+      return pendingRefresh;
+    },
+
     logout() {
+      // End the session on the server; local state is cleared regardless
+      const refreshToken = localStorage.getItem('refreshToken');
+      if (refreshToken) {
+        userService.logout(refreshToken).catch((error) => console.error(error));
+      }
+
       // Clear user state and localStorage on logout
       this.user = null;
       this.isAuthenticated = false;
       localStorage.removeItem('user');
+      localStorage.removeItem('accessToken');
+      localStorage.removeItem('refreshToken');
     },
 
     loadUserFromStorage() {